@@ -0,0 +1,459 @@
+package cache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	. "github.com/theshashankpal/api-collector/callgraph"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	. "github.com/theshashankpal/api-collector/logger"
+	"github.com/theshashankpal/api-collector/overlay"
+)
+
+var ccf = LogFields{Key: "layer", Value: "cache-callgraph"}
+
+// cacheVersion is bumped whenever the format of the cache file changes, older files are then ignored.
+const cacheVersion = 2
+
+// entry is a cached result, along with the hash of every file it was computed from:
+// the queried file, and the files the result points into.
+// The callers and the implementations of a function may be added in any file, so those results are
+// also bound to the hash of the whole workspace.
+type entry struct {
+	Files           map[string]string                  `json:"files"`
+	Workspace       string                             `json:"workspace,omitempty"`
+	OutgoingCalls   *CallHierarchyOutgoingCallResponse `json:"outgoingCalls,omitempty"`
+	IncomingCalls   *CallHierarchyIncomingCallResponse `json:"incomingCalls,omitempty"`
+	Implementations *ImplementationResponse            `json:"implementations,omitempty"`
+}
+
+type cacheFile struct {
+	Version int               `json:"version"`
+	Entries map[string]*entry `json:"entries"`
+}
+
+// CachedCallGraph is a CallGraph decorator which persists the results of OutgoingCalls, IncomingCalls
+// and Implementations on disk, keyed by file path, position and hash of the file contents.
+// An entry is dropped as soon as any file it was computed from is edited, and the results of IncomingCalls
+// and Implementations as soon as any Go file of the workspace is.
+//
+// The decorated CallGraph is only initialized on the first cache miss, so that a run against
+// an unchanged tree doesn't have to wait for gopls to load the workspace.
+type CachedCallGraph struct {
+	callGraph CallGraph
+	cachePath string
+	workDir   string
+	// overlay is hashed in place of the files it replaces.
+	overlay overlay.Overlay
+
+	entries   map[string]*entry
+	entriesMU *sync.Mutex
+
+	// Files don't change during a run, so every file is hashed only once.
+	hashes   map[string]string
+	hashesMU *sync.Mutex
+
+	workspaceOnce *sync.Once
+	workspace     string
+
+	initOnce *sync.Once
+	initErr  error
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+func NewCachedCallGraph(ctx context.Context, callGraph CallGraph, cachePath, workDir string, overlay overlay.Overlay) *CachedCallGraph {
+	Log(ctx, ccf).Trace().Msg(">>>> NewCachedCallGraph")
+	defer Log(ctx, ccf).Trace().Msg("<<<< NewCachedCallGraph")
+
+	Log(ctx, ccf).Debug().Str("cachePath", cachePath).Msg("Instantiating new cached call-graph")
+	return &CachedCallGraph{
+		callGraph:     callGraph,
+		cachePath:     cachePath,
+		workDir:       workDir,
+		overlay:       overlay,
+		entries:       make(map[string]*entry),
+		entriesMU:     new(sync.Mutex),
+		hashes:        make(map[string]string),
+		hashesMU:      new(sync.Mutex),
+		workspaceOnce: new(sync.Once),
+		initOnce:      new(sync.Once),
+	}
+}
+
+// Initialize loads the cache file, dropping the entries of edited files.
+// A missing or outdated cache file isn't an error, the cache simply starts empty.
+func (c *CachedCallGraph) Initialize(ctx context.Context) error {
+	Log(ctx, ccf).Trace().Msg(">>>> Initialize")
+	defer Log(ctx, ccf).Trace().Msg("<<<< Initialize")
+
+	data, err := os.ReadFile(c.cachePath)
+	if errors.Is(err, os.ErrNotExist) {
+		Log(ctx, ccf).Info().Str("cachePath", c.cachePath).Msg("No call-graph cache found, starting with an empty one")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("#Initialize: failed to read cache file %s -> %w", c.cachePath, err)
+	}
+
+	var file cacheFile
+	if err = json.Unmarshal(data, &file); err != nil || file.Version != cacheVersion {
+		Log(ctx, ccf).Warn().Str("cachePath", c.cachePath).Msg("Ignoring unreadable or outdated call-graph cache")
+		return nil
+	}
+
+	stale := 0
+	for key, cached := range file.Entries {
+		if !c.valid(cached) {
+			stale++
+			continue
+		}
+		c.entries[key] = cached
+	}
+
+	Log(ctx, ccf).Info().
+		Int("entries", len(c.entries)).
+		Int("stale", stale).
+		Msg("Call-graph cache loaded")
+	return nil
+}
+
+func (c *CachedCallGraph) OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyOutgoingCallResponse {
+	Log(ctx, ccf).Trace().Msg(">>>> OutgoingCalls")
+	defer Log(ctx, ccf).Trace().Msg("<<<< OutgoingCalls")
+
+	responseChan := make(chan *CallHierarchyOutgoingCallResponse, 1)
+	key := c.key("outgoingCalls", filePath, line, character)
+	if cached := c.lookup(key); cached != nil && cached.OutgoingCalls != nil {
+		responseChan <- cached.OutgoingCalls
+		return responseChan
+	}
+
+	if err := c.initialize(ctx); err != nil {
+		responseChan <- &CallHierarchyOutgoingCallResponse{Error: err}
+		return responseChan
+	}
+
+	go func() {
+		response := <-c.callGraph.OutgoingCalls(ctx, filePath, line, character)
+		if response.Error == nil {
+			uris := make([]string, 0, len(response.Result))
+			for _, call := range response.Result {
+				uris = append(uris, call.To.Uri)
+			}
+			c.store(key, filePath, uris, &entry{OutgoingCalls: response})
+		}
+		responseChan <- response
+	}()
+
+	return responseChan
+}
+
+func (c *CachedCallGraph) IncomingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyIncomingCallResponse {
+	Log(ctx, ccf).Trace().Msg(">>>> IncomingCalls")
+	defer Log(ctx, ccf).Trace().Msg("<<<< IncomingCalls")
+
+	responseChan := make(chan *CallHierarchyIncomingCallResponse, 1)
+	key := c.key("incomingCalls", filePath, line, character)
+	if cached := c.lookup(key); cached != nil && cached.IncomingCalls != nil {
+		responseChan <- cached.IncomingCalls
+		return responseChan
+	}
+
+	if err := c.initialize(ctx); err != nil {
+		responseChan <- &CallHierarchyIncomingCallResponse{Error: err}
+		return responseChan
+	}
+
+	go func() {
+		response := <-c.callGraph.IncomingCalls(ctx, filePath, line, character)
+		if response.Error == nil {
+			uris := make([]string, 0, len(response.Result))
+			for _, call := range response.Result {
+				uris = append(uris, call.From.Uri)
+			}
+			c.store(key, filePath, uris, &entry{Workspace: c.workspaceHash(), IncomingCalls: response})
+		}
+		responseChan <- response
+	}()
+
+	return responseChan
+}
+
+func (c *CachedCallGraph) Implementations(ctx context.Context, filePath string, line, character int) chan *ImplementationResponse {
+	Log(ctx, ccf).Trace().Msg(">>>> Implementations")
+	defer Log(ctx, ccf).Trace().Msg("<<<< Implementations")
+
+	responseChan := make(chan *ImplementationResponse, 1)
+	key := c.key("implementations", filePath, line, character)
+	if cached := c.lookup(key); cached != nil && cached.Implementations != nil {
+		responseChan <- cached.Implementations
+		return responseChan
+	}
+
+	if err := c.initialize(ctx); err != nil {
+		responseChan <- &ImplementationResponse{Error: err}
+		return responseChan
+	}
+
+	go func() {
+		response := <-c.callGraph.Implementations(ctx, filePath, line, character)
+		if response.Error == nil {
+			uris := make([]string, 0, len(response.Result))
+			for _, location := range response.Result {
+				uris = append(uris, location.Uri)
+			}
+			c.store(key, filePath, uris, &entry{Workspace: c.workspaceHash(), Implementations: response})
+		}
+		responseChan <- response
+	}()
+
+	return responseChan
+}
+
+func (c *CachedCallGraph) References(ctx context.Context, filePath string, line int, character int) chan *ReferenceResponse {
+	if err := c.initialize(ctx); err != nil {
+		responseChan := make(chan *ReferenceResponse, 1)
+		responseChan <- &ReferenceResponse{Error: err}
+		return responseChan
+	}
+	return c.callGraph.References(ctx, filePath, line, character)
+}
+
+func (c *CachedCallGraph) Hover(ctx context.Context, filePath string, line, character int) chan *HoverResponse {
+	if err := c.initialize(ctx); err != nil {
+		responseChan := make(chan *HoverResponse, 1)
+		responseChan <- &HoverResponse{Error: err}
+		return responseChan
+	}
+	return c.callGraph.Hover(ctx, filePath, line, character)
+}
+
+func (c *CachedCallGraph) DocumentSymbol(ctx context.Context, filePath string) chan *DocumentSymbolResponse {
+	if err := c.initialize(ctx); err != nil {
+		responseChan := make(chan *DocumentSymbolResponse, 1)
+		responseChan <- &DocumentSymbolResponse{Error: err}
+		return responseChan
+	}
+	return c.callGraph.DocumentSymbol(ctx, filePath)
+}
+
+func (c *CachedCallGraph) Definition(ctx context.Context, filePath string, line, character int) chan *DefinitionResponse {
+	if err := c.initialize(ctx); err != nil {
+		responseChan := make(chan *DefinitionResponse, 1)
+		responseChan <- &DefinitionResponse{Error: err}
+		return responseChan
+	}
+	return c.callGraph.Definition(ctx, filePath, line, character)
+}
+
+func (c *CachedCallGraph) TypeDefinition(ctx context.Context, filePath string, line, character int) chan *TypeDefinitionResponse {
+	if err := c.initialize(ctx); err != nil {
+		responseChan := make(chan *TypeDefinitionResponse, 1)
+		responseChan <- &TypeDefinitionResponse{Error: err}
+		return responseChan
+	}
+	return c.callGraph.TypeDefinition(ctx, filePath, line, character)
+}
+
+func (c *CachedCallGraph) WorkspaceSymbol(ctx context.Context, query string) chan *WorkspaceSymbolResponse {
+	if err := c.initialize(ctx); err != nil {
+		responseChan := make(chan *WorkspaceSymbolResponse, 1)
+		responseChan <- &WorkspaceSymbolResponse{Error: err}
+		return responseChan
+	}
+	return c.callGraph.WorkspaceSymbol(ctx, query)
+}
+
+func (c *CachedCallGraph) Shutdown(ctx context.Context) error {
+	return c.callGraph.Shutdown(ctx)
+}
+
+// Save writes the cache file, replacing the previous one atomically.
+func (c *CachedCallGraph) Save(ctx context.Context) error {
+	Log(ctx, ccf).Trace().Msg(">>>> Save")
+	defer Log(ctx, ccf).Trace().Msg("<<<< Save")
+
+	c.entriesMU.Lock()
+	data, err := json.Marshal(cacheFile{Version: cacheVersion, Entries: c.entries})
+	c.entriesMU.Unlock()
+	if err != nil {
+		return fmt.Errorf("#Save: failed to marshal the cache -> %w", err)
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(c.cachePath), filepath.Base(c.cachePath)+".*")
+	if err != nil {
+		return fmt.Errorf("#Save: failed to create the cache file -> %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err = tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("#Save: failed to write the cache file -> %w", err)
+	}
+	if err = tempFile.Close(); err != nil {
+		return fmt.Errorf("#Save: failed to write the cache file -> %w", err)
+	}
+	if err = os.Rename(tempFile.Name(), c.cachePath); err != nil {
+		return fmt.Errorf("#Save: failed to replace the cache file -> %w", err)
+	}
+
+	Log(ctx, ccf).Info().
+		Str("cachePath", c.cachePath).
+		Int64("hits", c.hits.Load()).
+		Int64("misses", c.misses.Load()).
+		Msg("Call-graph cache saved")
+	return nil
+}
+
+// Summary counts the cache hits and misses, along with the statistics of the decorated call-graph.
+func (c *CachedCallGraph) Summary() Summary {
+	summary := Summary{Counts: make(map[string]int)}
+	if reporter, ok := c.callGraph.(Reporter); ok {
+		summary = reporter.Summary()
+	}
+	summary.Counts["cacheHits"] = int(c.hits.Load())
+	summary.Counts["cacheMisses"] = int(c.misses.Load())
+	return summary
+}
+
+// initialize initializes the decorated call-graph, once.
+func (c *CachedCallGraph) initialize(ctx context.Context) *ResponseError {
+	c.initOnce.Do(func() {
+		Log(ctx, ccf).Debug().Msg("Cache miss, initializing the underlying call-graph")
+		c.initErr = c.callGraph.Initialize(ctx)
+	})
+
+	if c.initErr != nil {
+		return &ResponseError{
+			Code:    ServerNotInitialized,
+			Message: fmt.Sprintf("CachedCallGraph: failed to initialize the underlying call-graph -> %v", c.initErr),
+		}
+	}
+	return nil
+}
+
+func (c *CachedCallGraph) key(method, filePath string, line, character int) string {
+	return fmt.Sprintf("%s:%s:%d:%d:%s", method, filePath, line, character, c.hash(filePath))
+}
+
+func (c *CachedCallGraph) lookup(key string) *entry {
+	c.entriesMU.Lock()
+	cached, ok := c.entries[key]
+	c.entriesMU.Unlock()
+
+	if ok && c.valid(cached) {
+		c.hits.Add(1)
+		return cached
+	}
+
+	c.misses.Add(1)
+	return nil
+}
+
+func (c *CachedCallGraph) store(key, filePath string, uris []string, cached *entry) {
+	cached.Files = map[string]string{filePath: c.hash(filePath)}
+	for _, uri := range uris {
+		path := strings.TrimPrefix(uri, "file://")
+		cached.Files[path] = c.hash(path)
+	}
+
+	c.entriesMU.Lock()
+	c.entries[key] = cached
+	c.entriesMU.Unlock()
+}
+
+// valid tells whether none of the files the entry was computed from has changed.
+func (c *CachedCallGraph) valid(cached *entry) bool {
+	if cached.Workspace != "" && cached.Workspace != c.workspaceHash() {
+		return false
+	}
+	for path, hash := range cached.Files {
+		if c.hash(path) != hash {
+			return false
+		}
+	}
+	return true
+}
+
+// hash returns the hash of the file contents, empty if the file can't be read.
+func (c *CachedCallGraph) hash(path string) string {
+	c.hashesMU.Lock()
+	defer c.hashesMU.Unlock()
+
+	if hash, ok := c.hashes[path]; ok {
+		return hash
+	}
+
+	var hash string
+	if data, err := c.overlay.ReadFile(path); err == nil {
+		sum := sha256.Sum256(data)
+		hash = hex.EncodeToString(sum[:])
+	}
+	c.hashes[path] = hash
+	return hash
+}
+
+// workspaceHash returns the hash of every Go file of the work directory, along with go.mod and go.sum,
+// computed once as the hashes of single files are.
+func (c *CachedCallGraph) workspaceHash() string {
+	c.workspaceOnce.Do(func() {
+		paths := make(map[string]bool)
+		for _, path := range c.overlay.Files() {
+			if inWorkspace(c.workDir, path) {
+				paths[path] = true
+			}
+		}
+		_ = filepath.WalkDir(c.workDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return nil
+			}
+			if d.IsDir() {
+				// The go command ignores those directories too.
+				name := d.Name()
+				if path != c.workDir && (strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_") || name == "testdata") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if inWorkspace(c.workDir, path) {
+				paths[path] = true
+			}
+			return nil
+		})
+
+		sorted := make([]string, 0, len(paths))
+		for path := range paths {
+			sorted = append(sorted, path)
+		}
+		sort.Strings(sorted)
+
+		sum := sha256.New()
+		for _, path := range sorted {
+			fmt.Fprintf(sum, "%s %s\n", path, c.hash(path))
+		}
+		c.workspace = hex.EncodeToString(sum.Sum(nil))
+	})
+	return c.workspace
+}
+
+// inWorkspace tells whether the file is one the call-graph of the work directory depends on.
+func inWorkspace(workDir, path string) bool {
+	if !strings.HasPrefix(path, filepath.Clean(workDir)+string(filepath.Separator)) {
+		return false
+	}
+	name := filepath.Base(path)
+	return strings.HasSuffix(name, ".go") || name == "go.mod" || name == "go.sum"
+}
@@ -0,0 +1,208 @@
+package cache_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/cache"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+// countingCallGraph answers every OutgoingCalls with a single call to callee, and counts the calls it gets.
+type countingCallGraph struct {
+	callee          string
+	initialized     atomic.Int32
+	outgoingCalls   atomic.Int32
+	incomingCalls   atomic.Int32
+	implementations atomic.Int32
+	fail            bool
+}
+
+func (c *countingCallGraph) Initialize(ctx context.Context) error {
+	c.initialized.Add(1)
+	return nil
+}
+
+func (c *countingCallGraph) OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *requests.CallHierarchyOutgoingCallResponse {
+	c.outgoingCalls.Add(1)
+	responseChan := make(chan *requests.CallHierarchyOutgoingCallResponse, 1)
+	if c.fail {
+		responseChan <- &requests.CallHierarchyOutgoingCallResponse{Error: &requests.ResponseError{Code: requests.RequestFailed}}
+		return responseChan
+	}
+	responseChan <- &requests.CallHierarchyOutgoingCallResponse{
+		Result: []requests.CallHierarchyOutgoingCall{{To: requests.CallHierarchyItem{Name: "Callee", Uri: "file://" + c.callee}}},
+	}
+	return responseChan
+}
+
+func (c *countingCallGraph) IncomingCalls(ctx context.Context, filePath string, line, character int) chan *requests.CallHierarchyIncomingCallResponse {
+	c.incomingCalls.Add(1)
+	responseChan := make(chan *requests.CallHierarchyIncomingCallResponse, 1)
+	responseChan <- &requests.CallHierarchyIncomingCallResponse{Result: []requests.CallHierarchyIncomingCall{}}
+	return responseChan
+}
+
+func (c *countingCallGraph) Implementations(ctx context.Context, filePath string, line, character int) chan *requests.ImplementationResponse {
+	c.implementations.Add(1)
+	responseChan := make(chan *requests.ImplementationResponse, 1)
+	responseChan <- &requests.ImplementationResponse{Result: []requests.Location{}}
+	return responseChan
+}
+
+func (c *countingCallGraph) References(ctx context.Context, filePath string, line int, character int) chan *requests.ReferenceResponse {
+	responseChan := make(chan *requests.ReferenceResponse, 1)
+	responseChan <- &requests.ReferenceResponse{}
+	return responseChan
+}
+
+func (c *countingCallGraph) Hover(ctx context.Context, filePath string, line, character int) chan *requests.HoverResponse {
+	responseChan := make(chan *requests.HoverResponse, 1)
+	responseChan <- &requests.HoverResponse{}
+	return responseChan
+}
+
+func (c *countingCallGraph) DocumentSymbol(ctx context.Context, filePath string) chan *requests.DocumentSymbolResponse {
+	responseChan := make(chan *requests.DocumentSymbolResponse, 1)
+	responseChan <- &requests.DocumentSymbolResponse{}
+	return responseChan
+}
+
+func (c *countingCallGraph) Definition(ctx context.Context, filePath string, line, character int) chan *requests.DefinitionResponse {
+	responseChan := make(chan *requests.DefinitionResponse, 1)
+	responseChan <- &requests.DefinitionResponse{}
+	return responseChan
+}
+
+func (c *countingCallGraph) TypeDefinition(ctx context.Context, filePath string, line, character int) chan *requests.TypeDefinitionResponse {
+	responseChan := make(chan *requests.TypeDefinitionResponse, 1)
+	responseChan <- &requests.TypeDefinitionResponse{}
+	return responseChan
+}
+
+func (c *countingCallGraph) WorkspaceSymbol(ctx context.Context, query string) chan *requests.WorkspaceSymbolResponse {
+	responseChan := make(chan *requests.WorkspaceSymbolResponse, 1)
+	responseChan <- &requests.WorkspaceSymbolResponse{}
+	return responseChan
+}
+
+func (c *countingCallGraph) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+var _ = Describe("CachedCallGraph", func() {
+	var (
+		ctx        = context.Background()
+		dir        string
+		cachePath  string
+		callerPath string
+		calleePath string
+		inner      *countingCallGraph
+	)
+
+	newCachedCallGraph := func() *cache.CachedCallGraph {
+		inner = &countingCallGraph{callee: calleePath}
+		cachedCallGraph := cache.NewCachedCallGraph(ctx, inner, cachePath, dir, nil)
+		Expect(cachedCallGraph.Initialize(ctx)).To(Succeed())
+		return cachedCallGraph
+	}
+
+	outgoingCalls := func(cachedCallGraph *cache.CachedCallGraph) *requests.CallHierarchyOutgoingCallResponse {
+		response := <-cachedCallGraph.OutgoingCalls(ctx, callerPath, 3, 5)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+		Expect(response.Result[0].To.Name).To(Equal("Callee"))
+		return response
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "cache")
+		Expect(err).ToNot(HaveOccurred())
+		cachePath = filepath.Join(dir, "cache.json")
+		callerPath = filepath.Join(dir, "caller.go")
+		calleePath = filepath.Join(dir, "callee.go")
+		Expect(os.WriteFile(callerPath, []byte("package caller\n"), 0o644)).To(Succeed())
+		Expect(os.WriteFile(calleePath, []byte("package callee\n"), 0o644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(dir)).To(Succeed())
+	})
+
+	It("should answer repeated requests from the cache", func() {
+		cachedCallGraph := newCachedCallGraph()
+		outgoingCalls(cachedCallGraph)
+		outgoingCalls(cachedCallGraph)
+		Expect(inner.outgoingCalls.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should reuse the saved results without initializing the underlying call-graph", func() {
+		cachedCallGraph := newCachedCallGraph()
+		outgoingCalls(cachedCallGraph)
+		Expect(cachedCallGraph.Save(ctx)).To(Succeed())
+
+		cachedCallGraph = newCachedCallGraph()
+		outgoingCalls(cachedCallGraph)
+		Expect(inner.outgoingCalls.Load()).To(BeZero())
+		Expect(inner.initialized.Load()).To(BeZero())
+	})
+
+	It("should invalidate the results once the queried file is edited", func() {
+		cachedCallGraph := newCachedCallGraph()
+		outgoingCalls(cachedCallGraph)
+		Expect(cachedCallGraph.Save(ctx)).To(Succeed())
+
+		Expect(os.WriteFile(callerPath, []byte("package caller\n\nfunc F() {}\n"), 0o644)).To(Succeed())
+		cachedCallGraph = newCachedCallGraph()
+		outgoingCalls(cachedCallGraph)
+		Expect(inner.outgoingCalls.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should invalidate the results once a file they point into is edited", func() {
+		cachedCallGraph := newCachedCallGraph()
+		outgoingCalls(cachedCallGraph)
+		Expect(cachedCallGraph.Save(ctx)).To(Succeed())
+
+		Expect(os.WriteFile(calleePath, []byte("package callee\n\nfunc G() {}\n"), 0o644)).To(Succeed())
+		cachedCallGraph = newCachedCallGraph()
+		outgoingCalls(cachedCallGraph)
+		Expect(inner.outgoingCalls.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should invalidate the callers and the implementations once any Go file of the workspace is edited", func() {
+		cachedCallGraph := newCachedCallGraph()
+		Expect((<-cachedCallGraph.IncomingCalls(ctx, calleePath, 3, 5)).Error).To(BeNil())
+		Expect((<-cachedCallGraph.Implementations(ctx, calleePath, 3, 5)).Error).To(BeNil())
+		Expect(cachedCallGraph.Save(ctx)).To(Succeed())
+
+		cachedCallGraph = newCachedCallGraph()
+		Expect((<-cachedCallGraph.IncomingCalls(ctx, calleePath, 3, 5)).Error).To(BeNil())
+		Expect((<-cachedCallGraph.Implementations(ctx, calleePath, 3, 5)).Error).To(BeNil())
+		Expect(inner.incomingCalls.Load()).To(BeZero())
+		Expect(inner.implementations.Load()).To(BeZero())
+
+		// A new caller, in a file neither result points into.
+		Expect(os.WriteFile(filepath.Join(dir, "other.go"), []byte("package other\n"), 0o644)).To(Succeed())
+		cachedCallGraph = newCachedCallGraph()
+		Expect((<-cachedCallGraph.IncomingCalls(ctx, calleePath, 3, 5)).Error).To(BeNil())
+		Expect((<-cachedCallGraph.Implementations(ctx, calleePath, 3, 5)).Error).To(BeNil())
+		Expect(inner.incomingCalls.Load()).To(BeEquivalentTo(1))
+		Expect(inner.implementations.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should not cache errors", func() {
+		cachedCallGraph := newCachedCallGraph()
+		inner.fail = true
+		Expect((<-cachedCallGraph.OutgoingCalls(ctx, callerPath, 3, 5)).Error).ToNot(BeNil())
+
+		inner.fail = false
+		outgoingCalls(cachedCallGraph)
+		Expect(inner.outgoingCalls.Load()).To(BeEquivalentTo(2))
+	})
+})
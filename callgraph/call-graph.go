@@ -5,11 +5,34 @@ import (
 	"github.com/theshashankpal/api-collector/callgraph/lsp/requests"
 )
 
+// CallGraph positions are zero-based, their characters counting bytes as go/token does, whatever the backend.
 type CallGraph interface {
 	Initialize(ctx context.Context) error
 	OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *requests.CallHierarchyOutgoingCallResponse
+	IncomingCalls(ctx context.Context, filePath string, line, character int) chan *requests.CallHierarchyIncomingCallResponse
 	Implementations(ctx context.Context, filePath string, line, character int) chan *requests.ImplementationResponse
 	References(ctx context.Context, filePath string, line int, character int) chan *requests.ReferenceResponse
 	Hover(ctx context.Context, filePath string, line, character int) chan *requests.HoverResponse
 	DocumentSymbol(ctx context.Context, filePath string) chan *requests.DocumentSymbolResponse
+	// Definition and TypeDefinition jump from a use to the declaration of the symbol, or of its type,
+	// e.g. from the call of a function-typed field to the function type.
+	Definition(ctx context.Context, filePath string, line, character int) chan *requests.DefinitionResponse
+	TypeDefinition(ctx context.Context, filePath string, line, character int) chan *requests.TypeDefinitionResponse
+	// WorkspaceSymbol finds symbols by name across the workspace, e.g. `OntapAPIREST.VolumeCreate`.
+	WorkspaceSymbol(ctx context.Context, query string) chan *requests.WorkspaceSymbolResponse
+	// Shutdown releases what the call-graph holds, e.g. the gopls session, once the run is over.
+	Shutdown(ctx context.Context) error
+}
+
+// Reporter is implemented by the call-graphs keeping statistics of the run, which are logged once it is over.
+type Reporter interface {
+	Summary() Summary
+}
+
+// Summary gathers the statistics of a run, and the compile errors found in the analyzed tree along the way,
+// as the call-graph of a package which doesn't compile is likely to miss some calls.
+type Summary struct {
+	Counts map[string]int
+	// CompileErrors are formatted as "file:line:column: message".
+	CompileErrors []string
 }
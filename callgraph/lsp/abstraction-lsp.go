@@ -2,8 +2,13 @@ package lsp
 
 import (
 	"context"
-	"net"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+	"time"
 
+	"github.com/theshashankpal/api-collector/callgraph"
 	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
 	. "github.com/theshashankpal/api-collector/logger"
 )
@@ -14,16 +19,28 @@ var alf = LogFields{Key: "layer", Value: "abstraction-lsp"}
 type AbstractionLSP struct {
 	lspClient LSPInterface
 	name      string
+	positions *positions
+
+	retry     RetryPolicy
+	retries   map[string]int
+	exhausted map[string]int
+	retriesMU *sync.Mutex
 }
 
-func NewAbstractionLSP(ctx context.Context, conn net.Conn, workDir, name string) *AbstractionLSP {
+func NewAbstractionLSP(ctx context.Context, conn io.ReadWriteCloser, workDir, name string, config Config) *AbstractionLSP {
 	Log(ctx, alf).Trace().Msg(">>>> NewAbstractionLSP")
 	defer Log(ctx, alf).Trace().Msg("<<<< NewAbstractionLSP")
 
 	Log(ctx, alf).Debug().Msg("Instantiating new abstraction LSP")
+	lspClient := NewLsp(ctx, conn, workDir, config)
 	return &AbstractionLSP{
-		lspClient: NewLsp(ctx, conn, workDir),
+		lspClient: lspClient,
 		name:      name,
+		positions: newPositions(lspClient.PositionEncoding, config.Overlay.ReadFile),
+		retry:     config.Retry,
+		retries:   make(map[string]int),
+		exhausted: make(map[string]int),
+		retriesMU: new(sync.Mutex),
 	}
 }
 
@@ -31,48 +48,234 @@ func (l *AbstractionLSP) Initialize(ctx context.Context) error {
 	Log(ctx, alf).Trace().Msg(">>>> Initialize")
 	defer Log(ctx, alf).Trace().Msg("<<<< Initialize")
 
-	initializeRequest := InitializeRequest{}
-	initializedNotification := InitializedNotification{}
-	return l.lspClient.Initialize(ctx, l.name, &initializeRequest, &initializedNotification)
+	return l.lspClient.Initialize(ctx, l.name)
 }
 
 func (l *AbstractionLSP) OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyOutgoingCallResponse {
 	Log(ctx, alf).Trace().Msg(">>>> OutgoingCalls")
 	defer Log(ctx, alf).Trace().Msg("<<<< OutgoingCalls")
 
-	callHierarchyOutgoingCallRequest := CallHierarchyOutgoingCallRequest{}
-	callHierarchyPrepareRequest := CallHierarchyPrepareRequest{}
-	return l.lspClient.OutgoingCalls(ctx, filePath, line, character, &callHierarchyOutgoingCallRequest, &callHierarchyPrepareRequest)
+	character = l.positions.toServer(filePath, line, character)
+	return retrying(ctx, l, "OutgoingCalls", func() chan *CallHierarchyOutgoingCallResponse {
+		return l.lspClient.OutgoingCalls(ctx, filePath, line, character)
+	}, func(response *CallHierarchyOutgoingCallResponse) *ResponseError { return response.Error },
+		func(response *CallHierarchyOutgoingCallResponse) {
+			for i := range response.Result {
+				l.positions.fromServerItem(&response.Result[i].To)
+				// The calls are made from the function asked about.
+				for j := range response.Result[i].FromRanges {
+					l.positions.fromServerRange(filePath, &response.Result[i].FromRanges[j])
+				}
+			}
+		})
+}
+
+func (l *AbstractionLSP) IncomingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyIncomingCallResponse {
+	Log(ctx, alf).Trace().Msg(">>>> IncomingCalls")
+	defer Log(ctx, alf).Trace().Msg("<<<< IncomingCalls")
+
+	character = l.positions.toServer(filePath, line, character)
+	return retrying(ctx, l, "IncomingCalls", func() chan *CallHierarchyIncomingCallResponse {
+		return l.lspClient.IncomingCalls(ctx, filePath, line, character)
+	}, func(response *CallHierarchyIncomingCallResponse) *ResponseError { return response.Error },
+		func(response *CallHierarchyIncomingCallResponse) {
+			for i := range response.Result {
+				l.positions.fromServerItem(&response.Result[i].From)
+				// The calls are made from the caller.
+				for j := range response.Result[i].FromRanges {
+					l.positions.fromServerRange(filePathOf(response.Result[i].From.Uri), &response.Result[i].FromRanges[j])
+				}
+			}
+		})
 }
 
 func (l *AbstractionLSP) Implementations(ctx context.Context, filePath string, line, character int) chan *ImplementationResponse {
 	Log(ctx, alf).Trace().Msg(">>>> Implementations")
 	defer Log(ctx, alf).Trace().Msg("<<<< Implementations")
 
-	implementationRequest := ImplementationRequest{}
-	return l.lspClient.Implementations(ctx, filePath, line, character, &implementationRequest)
+	character = l.positions.toServer(filePath, line, character)
+	return retrying(ctx, l, "Implementations", func() chan *ImplementationResponse {
+		return l.lspClient.Implementations(ctx, filePath, line, character)
+	}, func(response *ImplementationResponse) *ResponseError { return response.Error },
+		func(response *ImplementationResponse) { l.positions.fromServerLocations(response.Result) })
 }
 
 func (l *AbstractionLSP) References(ctx context.Context, filePath string, line int, character int) chan *ReferenceResponse {
 	Log(ctx, alf).Trace().Msg(">>>> References")
 	defer Log(ctx, alf).Trace().Msg("<<<< References")
 
-	referencesRequest := ReferenceRequest{}
-	return l.lspClient.References(ctx, filePath, line, character, &referencesRequest)
+	character = l.positions.toServer(filePath, line, character)
+	return retrying(ctx, l, "References", func() chan *ReferenceResponse {
+		return l.lspClient.References(ctx, filePath, line, character)
+	}, func(response *ReferenceResponse) *ResponseError { return response.Error },
+		func(response *ReferenceResponse) { l.positions.fromServerLocations(response.Result) })
 }
 
 func (l *AbstractionLSP) Hover(ctx context.Context, filePath string, line, character int) chan *HoverResponse {
 	Log(ctx, alf).Trace().Msg(">>>> Hover")
 	defer Log(ctx, alf).Trace().Msg("<<<< Hover")
 
-	hoverRequest := HoverRequest{}
-	return l.lspClient.Hover(ctx, filePath, line, character, &hoverRequest)
+	character = l.positions.toServer(filePath, line, character)
+	return retrying(ctx, l, "Hover", func() chan *HoverResponse {
+		return l.lspClient.Hover(ctx, filePath, line, character)
+	}, func(response *HoverResponse) *ResponseError { return response.Error },
+		func(response *HoverResponse) {
+			if response.Result != nil && response.Result.Range != nil {
+				l.positions.fromServerRange(filePath, response.Result.Range)
+			}
+		})
 }
 
 func (l *AbstractionLSP) DocumentSymbol(ctx context.Context, filePath string) chan *DocumentSymbolResponse {
 	Log(ctx, alf).Trace().Msg(">>>> DocumentSymbol")
 	defer Log(ctx, alf).Trace().Msg("<<<< DocumentSymbol")
 
-	documentSymbolRequest := DocumentSymbolRequest{}
-	return l.lspClient.DocumentSymbol(ctx, filePath, &documentSymbolRequest)
+	return retrying(ctx, l, "DocumentSymbol", func() chan *DocumentSymbolResponse {
+		return l.lspClient.DocumentSymbol(ctx, filePath)
+	}, func(response *DocumentSymbolResponse) *ResponseError { return response.Error },
+		func(response *DocumentSymbolResponse) {
+			for i := range response.Result {
+				location := &response.Result[i].Location
+				l.positions.fromServerRange(filePathOf(location.Uri), &location.Range)
+			}
+		})
+}
+
+func (l *AbstractionLSP) Definition(ctx context.Context, filePath string, line, character int) chan *DefinitionResponse {
+	Log(ctx, alf).Trace().Msg(">>>> Definition")
+	defer Log(ctx, alf).Trace().Msg("<<<< Definition")
+
+	character = l.positions.toServer(filePath, line, character)
+	return retrying(ctx, l, "Definition", func() chan *DefinitionResponse {
+		return l.lspClient.Definition(ctx, filePath, line, character)
+	}, func(response *DefinitionResponse) *ResponseError { return response.Error },
+		func(response *DefinitionResponse) { l.positions.fromServerLocations(response.Result) })
+}
+
+func (l *AbstractionLSP) TypeDefinition(ctx context.Context, filePath string, line, character int) chan *TypeDefinitionResponse {
+	Log(ctx, alf).Trace().Msg(">>>> TypeDefinition")
+	defer Log(ctx, alf).Trace().Msg("<<<< TypeDefinition")
+
+	character = l.positions.toServer(filePath, line, character)
+	return retrying(ctx, l, "TypeDefinition", func() chan *TypeDefinitionResponse {
+		return l.lspClient.TypeDefinition(ctx, filePath, line, character)
+	}, func(response *TypeDefinitionResponse) *ResponseError { return response.Error },
+		func(response *TypeDefinitionResponse) { l.positions.fromServerLocations(response.Result) })
+}
+
+func (l *AbstractionLSP) WorkspaceSymbol(ctx context.Context, query string) chan *WorkspaceSymbolResponse {
+	Log(ctx, alf).Trace().Msg(">>>> WorkspaceSymbol")
+	defer Log(ctx, alf).Trace().Msg("<<<< WorkspaceSymbol")
+
+	return retrying(ctx, l, "WorkspaceSymbol", func() chan *WorkspaceSymbolResponse {
+		return l.lspClient.WorkspaceSymbol(ctx, query)
+	}, func(response *WorkspaceSymbolResponse) *ResponseError { return response.Error },
+		func(response *WorkspaceSymbolResponse) {
+			for i := range response.Result {
+				location := &response.Result[i].Location
+				l.positions.fromServerRange(filePathOf(location.Uri), &location.Range)
+			}
+		})
+}
+
+func (l *AbstractionLSP) Shutdown(ctx context.Context) error {
+	Log(ctx, alf).Trace().Msg(">>>> Shutdown")
+	defer Log(ctx, alf).Trace().Msg("<<<< Shutdown")
+
+	return l.lspClient.Shutdown(ctx)
+}
+
+// retrying sends the request again, as long as gopls answers it with a retryable error and the retry policy allows.
+// errorOf picks the error out of the response, every response type has its own. fromServer converts the positions
+// of the response finally answered, unless failed, into byte columns.
+func retrying[T any](ctx context.Context, l *AbstractionLSP, method string, send func() chan T,
+	errorOf func(T) *ResponseError, fromServer func(T)) chan T {
+	responseChan := make(chan T)
+
+	go func() {
+		backoff := l.retry.InitialBackoff
+		for retry := 0; ; retry++ {
+			response := <-send()
+			responseError := errorOf(response)
+			if responseError == nil {
+				fromServer(response)
+				responseChan <- response
+				return
+			}
+			if !retryable(responseError.Code) {
+				responseChan <- response
+				return
+			}
+
+			if retry >= l.retry.MaxRetries {
+				if l.retry.MaxRetries > 0 {
+					l.retriesMU.Lock()
+					l.exhausted[method]++
+					l.retriesMU.Unlock()
+				}
+				responseChan <- response
+				return
+			}
+
+			l.retriesMU.Lock()
+			l.retries[method]++
+			l.retriesMU.Unlock()
+			Log(ctx, alf).Debug().
+				Str("method", method).
+				Int("ErrorCode", responseError.Code).
+				Int("retry", retry+1).
+				Dur("backoff", backoff).
+				Msg("Retrying request")
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				responseChan <- response
+				return
+			}
+			if backoff *= 2; l.retry.MaxBackoff > 0 && backoff > l.retry.MaxBackoff {
+				backoff = l.retry.MaxBackoff
+			}
+		}
+	}()
+
+	return responseChan
+}
+
+// retryable tells whether the error is one gopls sends while it is still indexing, the request being worth sending again.
+func retryable(code int) bool {
+	return code == ContentModified || code == ServerCancelled
+}
+
+// Summary counts the retried requests, and those still failing once out of retries, per method.
+// It also lists the errors gopls found compiling the workspace.
+func (l *AbstractionLSP) Summary() callgraph.Summary {
+	l.retriesMU.Lock()
+	counts := map[string]int{"retries": 0, "retriesExhausted": 0}
+	for method, count := range l.retries {
+		counts["retries"] += count
+		counts[fmt.Sprintf("retries.%s", method)] = count
+	}
+	for method, count := range l.exhausted {
+		counts["retriesExhausted"] += count
+		counts[fmt.Sprintf("retriesExhausted.%s", method)] = count
+	}
+	l.retriesMU.Unlock()
+
+	var compileErrors []string
+	for filePath, diagnostics := range l.lspClient.Diagnostics() {
+		for _, diagnostic := range diagnostics {
+			if diagnostic.Severity != DiagnosticSeverityError {
+				continue
+			}
+			start := diagnostic.Range.Start
+			start.Character = l.positions.fromServer(filePath, start.Line, start.Character)
+			compileErrors = append(compileErrors,
+				fmt.Sprintf("%s:%d:%d: %s", filePath, start.Line+1, start.Character+1, diagnostic.Message))
+		}
+	}
+	sort.Strings(compileErrors)
+	counts["compileErrors"] = len(compileErrors)
+
+	return callgraph.Summary{Counts: counts, CompileErrors: compileErrors}
 }
@@ -0,0 +1,107 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+var _ = Describe("AbstractionLSP", func() {
+	const (
+		workDir = "/work/trident"
+		file    = workDir + "/storage_drivers/ontap/api/ontap_rest.go"
+
+		// failures is how many times the implementation request is turned down before being answered.
+		failures = 2
+	)
+
+	var (
+		ctx      = context.Background()
+		server   *lsptest.Server
+		client   *lsp.AbstractionLSP
+		received atomic.Int32
+		location = Location{Uri: "file://" + file, Range: Range{Start: Position{Line: 20, Character: 5}}}
+	)
+
+	BeforeEach(func() {
+		received.Store(0)
+
+		server = lsptest.NewServer()
+		server.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *ResponseError) {
+			if received.Add(1) <= failures {
+				return nil, &ResponseError{Code: ContentModified, Message: "content modified"}
+			}
+			return []Location{location}, nil
+		})
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	initialize := func(maxRetries int) {
+		retry := lsp.RetryPolicy{MaxRetries: maxRetries, InitialBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+		client = lsp.NewAbstractionLSP(ctx, server.Conn(), workDir, "trident", lsp.Config{Retry: retry})
+		Expect(client.Initialize(ctx)).To(Succeed())
+	}
+
+	It("should retry the requests turned down while gopls is indexing", func() {
+		initialize(3)
+
+		response := <-client.Implementations(ctx, file, 30, 2)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(ConsistOf(location))
+		Expect(received.Load()).To(BeEquivalentTo(3))
+
+		summary := client.Summary().Counts
+		Expect(summary).To(HaveKeyWithValue("retries", 2))
+		Expect(summary).To(HaveKeyWithValue("retries.Implementations", 2))
+		Expect(summary).To(HaveKeyWithValue("retriesExhausted", 0))
+	})
+
+	It("should give up once out of retries", func() {
+		initialize(1)
+
+		response := <-client.Implementations(ctx, file, 30, 2)
+		Expect(response.Error).ToNot(BeNil())
+		Expect(response.Error.Code).To(Equal(ContentModified))
+		Expect(received.Load()).To(BeEquivalentTo(2))
+		Expect(client.Summary().Counts).To(HaveKeyWithValue("retriesExhausted.Implementations", 1))
+	})
+
+	It("should not retry other errors", func() {
+		server.Handle("textDocument/hover", func(params json.RawMessage) (interface{}, *ResponseError) {
+			received.Add(1)
+			return nil, &ResponseError{Code: RequestFailed, Message: "no identifier found"}
+		})
+		initialize(3)
+
+		response := <-client.Hover(ctx, file, 30, 2)
+		Expect(response.Error.Code).To(Equal(RequestFailed))
+		Expect(received.Load()).To(BeEquivalentTo(1))
+		Expect(client.Summary().Counts).To(HaveKeyWithValue("retries", 0))
+	})
+	It("should list the compile errors published by gopls", func() {
+		initialize(0)
+
+		Expect(server.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			Uri: "file://" + file,
+			Diagnostics: []Diagnostic{
+				{Range: Range{Start: Position{Line: 9, Character: 1}}, Severity: DiagnosticSeverityError, Message: "undefined: x"},
+				{Range: Range{Start: Position{Line: 3, Character: 0}}, Severity: DiagnosticSeverityWarning, Message: "unused"},
+			},
+		})).To(Succeed())
+
+		Eventually(func() []string { return client.Summary().CompileErrors }).
+			Should(ConsistOf(file + ":10:2: undefined: x"))
+		Expect(client.Summary().Counts).To(HaveKeyWithValue("compileErrors", 1))
+	})
+})
@@ -0,0 +1,83 @@
+package lsp
+
+import (
+	"context"
+	"io"
+	"time"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/overlay"
+)
+
+// Config holds the optional settings of the LSP client, its zero value is the default behaviour.
+type Config struct {
+	// Recorder records every request and response passing through the Requester, nil records nothing.
+	Recorder *Recorder
+
+	// RequestTimeout bounds every request whose context has no deadline of its own, zero waits forever.
+	RequestTimeout time.Duration
+
+	// ReadyTimeout bounds the wait for gopls to load the workspace, zero waits forever.
+	ReadyTimeout time.Duration
+
+	// ReadyQuietPeriod is how long gopls must have no progress active, once some has begun, for the workspace
+	// to be loaded, as it ends a progress before beginning the next one. Zero is DefaultReadyQuietPeriod.
+	ReadyQuietPeriod time.Duration
+
+	// ReadyIdleTimeout is how long to wait for gopls to begin reporting progress, the workspace being taken as
+	// loaded if it never does. Zero is DefaultReadyIdleTimeout.
+	ReadyIdleTimeout time.Duration
+
+	// Concurrency is how many requests are kept in flight at most, the others wait for their turn. Zero doesn't limit them.
+	Concurrency int
+
+	// Dial opens a new connection to gopls when the current one drops, at most MaxReconnects times over the run.
+	// The requests in flight are then sent again on the new connection. Nil never reconnects.
+	Dial          func(ctx context.Context) (io.ReadWriteCloser, error)
+	MaxReconnects int
+
+	// Settings are handed over to gopls, at initialization and whenever it asks for its configuration.
+	Settings GoplsSettings
+
+	// Overlay is opened in gopls on every session, so that it analyzes the overlaid files rather than those on disk.
+	Overlay overlay.Overlay
+
+	// Retry is applied by AbstractionLSP to the requests gopls turns down while it is still indexing.
+	Retry RetryPolicy
+}
+
+const (
+	DefaultReadyQuietPeriod = 200 * time.Millisecond
+	DefaultReadyIdleTimeout = 10 * time.Second
+)
+
+// readyQuietPeriod is the ReadyQuietPeriod, its default if unset.
+func (c Config) readyQuietPeriod() time.Duration {
+	if c.ReadyQuietPeriod > 0 {
+		return c.ReadyQuietPeriod
+	}
+	return DefaultReadyQuietPeriod
+}
+
+// readyIdleTimeout is the ReadyIdleTimeout, its default if unset.
+func (c Config) readyIdleTimeout() time.Duration {
+	if c.ReadyIdleTimeout > 0 {
+		return c.ReadyIdleTimeout
+	}
+	return DefaultReadyIdleTimeout
+}
+
+// RetryPolicy retries the requests answered with ContentModified or ServerCancelled, waiting InitialBackoff
+// before the first retry and doubling it for every next one, up to MaxBackoff. Zero MaxRetries never retries.
+type RetryPolicy struct {
+	MaxRetries     int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// DefaultRetryPolicy gives gopls a few seconds to settle.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries:     5,
+	InitialBackoff: 200 * time.Millisecond,
+	MaxBackoff:     5 * time.Second,
+}
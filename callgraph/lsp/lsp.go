@@ -1,10 +1,12 @@
 package lsp
 
 import (
-	"bufio"
 	"context"
+	"encoding/json"
 	"fmt"
-	"net"
+	"io"
+	"sync"
+	"sync/atomic"
 
 	"github.com/google/uuid"
 
@@ -15,183 +17,285 @@ import (
 var lf = LogFields{Key: "layer", Value: "lsp"}
 
 type LSPInterface interface {
-	Initialize(ctx context.Context, name string,
-		initializeRequest InitializeRequestInterface,
-		initializedNotification InitializedNotificationInterface) error
-	OutgoingCalls(ctx context.Context, filePath string, line, character int,
-		callHierarchyOutgoingCallRequest CallHierarchyOutgoingCallRequestInterface,
-		callHierarchyPrepareRequest CallHierarchyPrepareRequestInterface) chan *CallHierarchyOutgoingCallResponse
-	Implementations(ctx context.Context, filePath string, line, character int,
-		implementationRequest ImplementationRequestInterface) chan *ImplementationResponse
-	References(ctx context.Context, filePath string, line int, character int,
-		referencesRequest ReferenceRequestIntercace) chan *ReferenceResponse
-	Hover(ctx context.Context, fileName string, line, character int, hoverRequest HoverRequestInterface) chan *HoverResponse
-	DocumentSymbol(ctx context.Context, filePath string,
-		documentSymbolRequest DocumentSymbolRequestInterface) chan *DocumentSymbolResponse
+	Initialize(ctx context.Context, name string) error
+	OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyOutgoingCallResponse
+	IncomingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyIncomingCallResponse
+	Implementations(ctx context.Context, filePath string, line, character int) chan *ImplementationResponse
+	References(ctx context.Context, filePath string, line int, character int) chan *ReferenceResponse
+	Hover(ctx context.Context, fileName string, line, character int) chan *HoverResponse
+	DocumentSymbol(ctx context.Context, filePath string) chan *DocumentSymbolResponse
+	Definition(ctx context.Context, filePath string, line, character int) chan *DefinitionResponse
+	TypeDefinition(ctx context.Context, filePath string, line, character int) chan *TypeDefinitionResponse
+	WorkspaceSymbol(ctx context.Context, query string) chan *WorkspaceSymbolResponse
+	// Diagnostics are the last ones the server published, keyed by file path.
+	Diagnostics() map[string][]Diagnostic
+	PositionEncoding() PositionEncodingKind
+	Shutdown(ctx context.Context) error
 }
 
 type LSP struct {
-	conn         net.Conn
-	reader       *bufio.Reader
-	workdir      string
-	initialized  bool
-	requestChan  chan Request
-	responseChan chan Request
-	requester    *Requester
+	workdir     string
+	initialized bool
+	config      Config
+
+	// session is the connection to gopls, replaced by reconnect if it drops.
+	session   *session
+	sessionMU *sync.RWMutex
+	// name is kept to initialize the sessions reconnect opens.
+	name         string
+	reconnects   int
+	reconnectMU  *sync.Mutex
+	shuttingDown atomic.Bool
+
+	// inFlight holds a slot per request sent and not answered yet, nil doesn't limit them.
+	inFlight chan struct{}
+
+	diagnostics   map[string][]Diagnostic
+	diagnosticsMU *sync.Mutex
 }
 
-func NewLsp(ctx context.Context, conn net.Conn, workDir string) *LSP {
+func NewLsp(ctx context.Context, conn io.ReadWriteCloser, workDir string, config Config) *LSP {
 	Log(ctx, lf).Trace().Msg(">>>> NewLsp")
 	defer Log(ctx, lf).Trace().Msg("<<<< NewLsp")
 
 	Log(ctx, lf).Debug().Msg("Instantiating new concrete LSP")
-	return &LSP{
-		conn:    conn,
-		workdir: workDir,
-		reader:  bufio.NewReader(conn),
+	l := &LSP{
+		workdir:       workDir,
+		config:        config,
+		session:       newSession(conn, config.readyQuietPeriod()),
+		sessionMU:     new(sync.RWMutex),
+		reconnectMU:   new(sync.Mutex),
+		diagnostics:   make(map[string][]Diagnostic),
+		diagnosticsMU: new(sync.Mutex),
 	}
+	if config.Concurrency > 0 {
+		l.inFlight = make(chan struct{}, config.Concurrency)
+	}
+	return l
 }
 
-func (l *LSP) Initialize(ctx context.Context, name string,
-	initializeRequest InitializeRequestInterface,
-	initializedNotification InitializedNotificationInterface) error {
+func (l *LSP) Initialize(ctx context.Context, name string) error {
 	Log(ctx, lf).Trace().Msg(">>>> Initialize")
 	defer Log(ctx, lf).Trace().Msg("<<<< Initialize")
 
-	// Sending initialize request
-	Log(ctx, lf).Debug().Str("workDir", l.workdir).Msg("Sending initialize request")
-	initializeRequest = initializeRequest.NewRequest(l.workdir, name, int(uuid.New().ID()))
-	err := initializeRequest.SendRequest(l.conn)
-	if err != nil {
-		return err
-	}
-
-	initializeResponse, err := initializeRequest.ReadResponse(l.reader)
-	if err != nil {
+	l.name = name
+	if err := l.start(ctx, l.current()); err != nil {
 		return err
 	}
 
-	if initializeResponse.Error != nil {
-		return fmt.Errorf("#Initialize: failed to get initialize response -> %w", initializeResponse.Error)
-	}
-
-	// Sending initialized notification
-	initializedNotification = initializedNotification.NewNotification()
-	err = initializedNotification.SendRequest(l.conn)
-	if err != nil {
-		return err
-	}
-	err = initializedNotification.ReadResponse(l.reader)
-	if err != nil {
-		return err
-	}
-
-	// Start the requester after initialization.
-	l.requestChan = make(chan Request, 10)
-	l.responseChan = make(chan Request, 10)
-	l.requester = NewRequester(l.reader, l.requestChan, l.responseChan, l.conn)
-
 	l.initialized = true
 	return nil
 }
 
-func (l *LSP) OutgoingCalls(ctx context.Context, filePath string, line, character int,
-	callHierarchyOutgoingCallRequest CallHierarchyOutgoingCallRequestInterface,
-	callHierarchyPrepareRequest CallHierarchyPrepareRequestInterface) chan *CallHierarchyOutgoingCallResponse {
-
+func (l *LSP) OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyOutgoingCallResponse {
 	// Create a channel to send back the response
 	callHierarchyOutgoingCallChan := make(chan *CallHierarchyOutgoingCallResponse)
 
 	go func() {
-		callHierarchyPrepareResponseChan := make(chan *CallHierarchyPrepareResponse)
-		callHierarchyPrepareRequest = callHierarchyPrepareRequest.NewRequest(filePath, line, character, int(uuid.New().ID()))
-		responseChan := make(chan map[string]interface{})
-		go callHierarchyPrepareRequest.SendRequest(l.requestChan, responseChan)
-		go callHierarchyPrepareRequest.ReadResponse(callHierarchyPrepareResponseChan, responseChan)
-
-		// Wait for the call hierarchy prepare response
-		callHierarchyPrepareResponse := <-callHierarchyPrepareResponseChan
-		if callHierarchyPrepareResponse.Error != nil {
-			// Send back the error
-			tempCallHierarchyOutgoingCallChan := &CallHierarchyOutgoingCallResponse{
-				Error: &ResponseError{
-					Code:    callHierarchyPrepareResponse.Error.Code,
-					Message: fmt.Sprintf("OutgoingCalls: failed to get call hierarchy prepare response -> %v", callHierarchyPrepareResponse.Error.Error()),
-				},
-			}
-
-			callHierarchyOutgoingCallChan <- tempCallHierarchyOutgoingCallChan
+		item, responseError := l.prepareCallHierarchy(ctx, "OutgoingCalls", filePath, line, character)
+		if responseError != nil {
+			callHierarchyOutgoingCallChan <- &CallHierarchyOutgoingCallResponse{Error: responseError}
 			return
 		}
 
 		// Now need to get the actual outgoing calls
-		if len(callHierarchyPrepareResponse.Result) == 0 {
-			// Send back the error
-			tempCallHierarchyOutgoingCallChan := &CallHierarchyOutgoingCallResponse{
-				Error: &ResponseError{
-					Code:    EmptyCallHierarchPrepareResponse,
-					Message: fmt.Sprintf("OutgoingCalls: call hierarchy prerpare resonse is empty"),
-				},
-			}
+		callHierarchyOutgoingCallChan <- <-send[[]CallHierarchyOutgoingCall](ctx, l, "callHierarchy/outgoingCalls",
+			CallHierarchyOutgoingCallsParams{Item: *item})
+	}()
+
+	return callHierarchyOutgoingCallChan
+}
+
+func (l *LSP) IncomingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyIncomingCallResponse {
+	// Create a channel to send back the response
+	callHierarchyIncomingCallChan := make(chan *CallHierarchyIncomingCallResponse)
 
-			callHierarchyOutgoingCallChan <- tempCallHierarchyOutgoingCallChan
+	go func() {
+		item, responseError := l.prepareCallHierarchy(ctx, "IncomingCalls", filePath, line, character)
+		if responseError != nil {
+			callHierarchyIncomingCallChan <- &CallHierarchyIncomingCallResponse{Error: responseError}
 			return
 		}
-		callHierarchyOutgoingCallRequest = callHierarchyOutgoingCallRequest.NewRequest(callHierarchyPrepareResponse.Result[0], int(uuid.New().ID()))
-		responseChan = make(chan map[string]interface{})
-		go callHierarchyOutgoingCallRequest.SendRequest(l.requestChan, responseChan)
-		go callHierarchyOutgoingCallRequest.ReadResponse(callHierarchyOutgoingCallChan, responseChan)
+
+		// Now need to get the actual incoming calls
+		callHierarchyIncomingCallChan <- <-send[[]CallHierarchyIncomingCall](ctx, l, "callHierarchy/incomingCalls",
+			CallHierarchyIncomingCallsParams{Item: *item})
 	}()
 
-	return callHierarchyOutgoingCallChan
+	return callHierarchyIncomingCallChan
 }
 
-func (l *LSP) Implementations(ctx context.Context, filePath string, line, character int,
-	implementationRequest ImplementationRequestInterface) chan *ImplementationResponse {
+// prepareCallHierarchy returns the call hierarchy item of the function at the position, which the calls
+// are then asked about. caller names the method asking, in the error.
+func (l *LSP) prepareCallHierarchy(ctx context.Context, caller, filePath string, line, character int) (*CallHierarchyItem, *ResponseError) {
+	callHierarchyPrepareResponse := <-send[[]CallHierarchyItem](ctx, l, "textDocument/prepareCallHierarchy",
+		CallHierarchyPrepareParams{TextDocumentPositionParams: positionParams(filePath, line, character)})
+	if callHierarchyPrepareResponse.Error != nil {
+		return nil, &ResponseError{
+			Code:    callHierarchyPrepareResponse.Error.Code,
+			Message: fmt.Sprintf("%s: failed to get call hierarchy prepare response -> %v", caller, callHierarchyPrepareResponse.Error.Error()),
+		}
+	}
+	if len(callHierarchyPrepareResponse.Result) == 0 {
+		return nil, &ResponseError{
+			Code:    EmptyCallHierarchPrepareResponse,
+			Message: fmt.Sprintf("%s: call hierarchy prepare response is empty", caller),
+		}
+	}
+	return &callHierarchyPrepareResponse.Result[0], nil
+}
 
-	var implementationResponseChan = make(chan *ImplementationResponse)
+func (l *LSP) Implementations(ctx context.Context, filePath string, line, character int) chan *ImplementationResponse {
+	return send[[]Location](ctx, l, "textDocument/implementation",
+		ImplementationParams{TextDocumentPositionParams: positionParams(filePath, line, character)})
+}
 
-	implementationRequest = implementationRequest.NewRequest(filePath, line, character, int(uuid.New().ID()))
+func (l *LSP) References(ctx context.Context, filePath string, line int, character int) chan *ReferenceResponse {
+	return send[[]Location](ctx, l, "textDocument/references", ReferencesParams{
+		TextDocumentPositionParams: positionParams(filePath, line, character),
+		Context:                    ReferenceContext{IncludeDeclaration: false},
+	})
+}
 
-	tempResponseChan := make(chan map[string]interface{})
-	go implementationRequest.SendRequest(l.requestChan, tempResponseChan)
-	go implementationRequest.ReadResponse(implementationResponseChan, tempResponseChan)
+func (l *LSP) Hover(ctx context.Context, fileName string, line, character int) chan *HoverResponse {
+	return send[*Hover](ctx, l, "textDocument/hover",
+		HoverParams{TextDocumentPositionParams: positionParams(fileName, line, character)})
+}
 
-	return implementationResponseChan
+func (l *LSP) DocumentSymbol(ctx context.Context, filePath string) chan *DocumentSymbolResponse {
+	return send[[]DocumentSymbolResult](ctx, l, "textDocument/documentSymbol",
+		DocumentSymbolParams{TextDocument: TextDocumentIdentifier{Uri: fmt.Sprintf("file://%s", filePath)}})
 }
 
-func (l *LSP) References(ctx context.Context, filePath string, line int, character int,
-	referencesRequest ReferenceRequestIntercace) chan *ReferenceResponse {
-	var referencesResponseChan = make(chan *ReferenceResponse)
+func (l *LSP) Definition(ctx context.Context, filePath string, line, character int) chan *DefinitionResponse {
+	return send[Locations](ctx, l, "textDocument/definition",
+		DefinitionParams{TextDocumentPositionParams: positionParams(filePath, line, character)})
+}
+
+func (l *LSP) TypeDefinition(ctx context.Context, filePath string, line, character int) chan *TypeDefinitionResponse {
+	return send[Locations](ctx, l, "textDocument/typeDefinition",
+		TypeDefinitionParams{TextDocumentPositionParams: positionParams(filePath, line, character)})
+}
 
-	referencesRequest = referencesRequest.NewRequest(filePath, line, character, int(uuid.New().ID()))
+func (l *LSP) WorkspaceSymbol(ctx context.Context, query string) chan *WorkspaceSymbolResponse {
+	return send[[]SymbolInformation](ctx, l, "workspace/symbol", WorkspaceSymbolParams{Query: query})
+}
 
-	go referencesRequest.SendRequest(l.requestChan)
-	go referencesRequest.ReadResponse(referencesResponseChan)
+// positionParams points at the position of the file, zero-based.
+func positionParams(filePath string, line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{Uri: fmt.Sprintf("file://%s", filePath)},
+		Position:     Position{Line: line, Character: character},
+	}
+}
 
-	return referencesResponseChan
+// Call sends a request of the given method, and decodes its result straight into R, for the methods
+// LSPInterface doesn't have.
+// It is a function rather than a method, methods can't have type parameters of their own.
+func Call[R, P any](ctx context.Context, l *LSP, method string, params P) (R, error) {
+	Log(ctx, lf).Trace().Str("method", method).Msg(">>>> Call")
+	defer Log(ctx, lf).Trace().Str("method", method).Msg("<<<< Call")
+
+	response := <-send[R](ctx, l, method, params)
+	if response.Error != nil {
+		var zero R
+		return zero, fmt.Errorf("#Call: %s failed -> %w", method, response.Error)
+	}
+	return response.Result, nil
 }
 
-func (l *LSP) Hover(ctx context.Context, fileName string, line, character int, hoverRequest HoverRequestInterface) chan *HoverResponse {
-	var hoverResponseChan = make(chan *HoverResponse)
+// Notify sends a notification of the given method, once written to the connection. Unlike requests,
+// notifications aren't sent again on reconnection. It fails if the session isn't started yet, nothing would send it.
+func Notify[P any](ctx context.Context, l *LSP, method string, params P) error {
+	Log(ctx, lf).Trace().Str("method", method).Msg(">>>> Notify")
+	defer Log(ctx, lf).Trace().Str("method", method).Msg("<<<< Notify")
+
+	s := l.current()
+	if !s.started.Load() {
+		return fmt.Errorf("#Notify: %s not sent, the session isn't started", method)
+	}
+	NewGenericNotification(method, params).SendRequest(s.requestChan)
+	return nil
+}
 
-	hoverRequest = hoverRequest.NewRequest(fileName, line, character, int(uuid.New().ID()))
+// send sends a request of the given method, whose response has its result decoded into R. Every request goes
+// through await, so it is limited, timed out, cancelled and sent again on reconnection alike.
+func send[R, P any](ctx context.Context, l *LSP, method string, params P) chan *GenericResponse[R] {
+	responseChan := make(chan *GenericResponse[R])
 
-	go hoverRequest.SendRequest(l.requestChan)
-	go hoverRequest.ReadResponse(hoverResponseChan)
+	id := int(uuid.New().ID())
+	request := NewGenericRequest(method, params, id)
+	toReader := l.await(ctx, id, func(requestChan chan Request, fromRequester chan json.RawMessage) {
+		request.SendRequest(requestChan, fromRequester)
+	})
+	go func() {
+		responseChan <- ReadGenericResponse[R](toReader)
+	}()
 
-	return hoverResponseChan
+	return responseChan
 }
 
-func (l *LSP) DocumentSymbol(ctx context.Context, filePath string,
-	documentSymbolRequest DocumentSymbolRequestInterface) chan *DocumentSymbolResponse {
+// await sends the request with the given ID once one of the in-flight slots is free, then stands between
+// the Requester and the reader of the response, forwarding its response unless ctx is done first.
+// The request is then cancelled, on the Requester and on the server with a $/cancelRequest notification,
+// and the reader gets a RequestCancelled error instead.
+// If the connection drops before the response, the request is sent again once reconnected.
+func (l *LSP) await(ctx context.Context, id int,
+	write func(requestChan chan Request, fromRequester chan json.RawMessage)) chan json.RawMessage {
+	toReader := make(chan json.RawMessage)
+
+	cancelled := func(err error) json.RawMessage {
+		return ErrorResponse(id, RequestCancelled, fmt.Sprintf("request %d cancelled -> %v", id, err))
+	}
 
-	var documentSymbolResponseChan = make(chan *DocumentSymbolResponse)
+	go func() {
+		// Waiting for a slot isn't bounded by RequestTimeout, the request isn't sent yet.
+		if l.inFlight != nil {
+			select {
+			case l.inFlight <- struct{}{}:
+				defer func() { <-l.inFlight }()
+			case <-ctx.Done():
+				toReader <- cancelled(ctx.Err())
+				return
+			}
+		}
 
-	documentSymbolRequest = documentSymbolRequest.NewRequest(filePath, int(uuid.New().ID()))
+		var cancel context.CancelFunc = func() {}
+		if _, ok := ctx.Deadline(); !ok && l.config.RequestTimeout > 0 {
+			ctx, cancel = context.WithTimeout(ctx, l.config.RequestTimeout)
+		}
+		defer cancel()
+
+		for {
+			s := l.current()
+			// Buffered, so the Requester never blocks on a response nobody waits for anymore.
+			fromRequester := make(chan json.RawMessage, 1)
+			go write(s.requestChan, fromRequester)
+
+			select {
+			case response := <-fromRequester:
+				if s.requester.Closed() && connectionClosed(response) && l.reconnect(ctx, s) == nil {
+					Log(ctx, lf).Debug().Int("id", id).Msg("Sending request again on the new session")
+					continue
+				}
+				toReader <- response
+			case <-ctx.Done():
+				Log(ctx, lf).Debug().Int("id", id).Err(ctx.Err()).Msg("Cancelling request")
+				s.requester.Cancel(id)
+				go NewGenericNotification("$/cancelRequest", CancelParams{ID: id}).SendRequest(s.requestChan)
+				toReader <- cancelled(ctx.Err())
+			}
+			return
+		}
+	}()
 
-	go documentSymbolRequest.SendRequest(l.requestChan)
-	go documentSymbolRequest.ReadResponse(documentSymbolResponseChan)
+	return toReader
+}
 
-	return documentSymbolResponseChan
+// connectionClosed tells whether the Requester failed the request as the connection dropped.
+func connectionClosed(response json.RawMessage) bool {
+	var message struct {
+		Error *ResponseError `json:"error"`
+	}
+	return json.Unmarshal(response, &message) == nil && message.Error != nil && message.Error.Code == ConnectionClosed
 }
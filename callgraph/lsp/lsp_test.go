@@ -0,0 +1,583 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/overlay"
+)
+
+var _ = Describe("LSP", func() {
+	const (
+		workDir = "/work/trident"
+		file    = workDir + "/storage_drivers/ontap/api/ontap_rest.go"
+		detail  = "github.com/netapp/trident/storage_drivers/ontap/api • ontap_rest.go"
+	)
+
+	var (
+		ctx    = context.Background()
+		server *lsptest.Server
+		client *lsp.LSP
+
+		volumeCreate = lsptest.NewFunction(file, 10, 5, "VolumeCreate", detail)
+		volumeGet    = lsptest.NewFunction(file, 20, 5, "VolumeGet", detail)
+		iface        = lsptest.NewFunction(file, 30, 1, "VolumeList", detail)
+	)
+
+	BeforeEach(func() {
+		volumeCreate.Calls = []CallHierarchyItem{volumeGet.Item}
+		iface.Implementations = []Location{volumeGet.Location()}
+
+		server = lsptest.NewServer()
+		server.Script(volumeCreate, volumeGet, iface)
+
+		client = lsp.NewLsp(ctx, server.Conn(), workDir, lsp.Config{})
+		Expect(client.Initialize(ctx, "trident")).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should initialize the workspace folder", func() {
+		initialize := server.ReceivedMethod("initialize")
+		Expect(initialize).To(HaveLen(1))
+
+		var params InitializeParams
+		Expect(json.Unmarshal(initialize[0].Params, &params)).To(Succeed())
+		Expect(params.WorkspaceFolders).To(ConsistOf(WorkspaceFolder{URI: "file://" + workDir, Name: "trident"}))
+		Expect(params.Capabilities.Window.WorkDoneProgress).To(BeTrue())
+		Expect(server.ReceivedMethod("initialized")).To(HaveLen(1))
+		Expect(server.ReceivedMethod("initialized")[0].ID).To(BeNil())
+	})
+
+	It("should return the outgoing calls of a function", func() {
+		response := <-client.OutgoingCalls(ctx, file, 10, 5)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+		Expect(response.Result[0].To).To(Equal(volumeGet.Item))
+	})
+
+	It("should return the incoming calls of a function", func() {
+		response := <-client.IncomingCalls(ctx, file, 20, 5)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+		Expect(response.Result[0].From).To(Equal(volumeCreate.Item))
+	})
+
+	It("should return an error if nothing is found at the position", func() {
+		response := <-client.OutgoingCalls(ctx, file, 1, 1)
+		Expect(response.Error).ToNot(BeNil())
+		Expect(response.Error.Code).To(Equal(EmptyCallHierarchPrepareResponse))
+	})
+
+	It("should return the error sent by the server", func() {
+		server.Handle("textDocument/prepareCallHierarchy", func(params json.RawMessage) (interface{}, *ResponseError) {
+			return nil, &ResponseError{Code: ContentModified, Message: "content modified"}
+		})
+
+		response := <-client.OutgoingCalls(ctx, file, 10, 5)
+		Expect(response.Error).ToNot(BeNil())
+		Expect(response.Error.Code).To(Equal(ContentModified))
+	})
+
+	It("should return the definition, sent as a single location", func() {
+		server.Handle("textDocument/definition", func(params json.RawMessage) (interface{}, *ResponseError) {
+			return volumeGet.Location(), nil
+		})
+
+		response := <-client.Definition(ctx, file, 11, 10)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(ConsistOf(volumeGet.Location()))
+	})
+
+	It("should find symbols by name", func() {
+		symbol := SymbolInformation{
+			Name:          "api.RestClient.VolumeCreate",
+			Kind:          SymbolKindMethod,
+			Location:      volumeCreate.Location(),
+			ContainerName: "github.com/netapp/trident/storage_drivers/ontap/api",
+		}
+		server.Handle("workspace/symbol", func(params json.RawMessage) (interface{}, *ResponseError) {
+			var workspaceSymbolParams WorkspaceSymbolParams
+			Expect(json.Unmarshal(params, &workspaceSymbolParams)).To(Succeed())
+			Expect(workspaceSymbolParams.Query).To(Equal("RestClient.VolumeCreate"))
+			return []SymbolInformation{symbol}, nil
+		})
+
+		response := <-client.WorkspaceSymbol(ctx, "RestClient.VolumeCreate")
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(ConsistOf(symbol))
+	})
+
+	It("should return the references of a function", func() {
+		server.Handle("textDocument/references", func(params json.RawMessage) (interface{}, *ResponseError) {
+			var referencesParams ReferencesParams
+			Expect(json.Unmarshal(params, &referencesParams)).To(Succeed())
+			Expect(referencesParams.TextDocument.Uri).To(Equal("file://" + file))
+			Expect(referencesParams.Position).To(Equal(Position{Line: 20, Character: 5}))
+			return []Location{volumeCreate.Location()}, nil
+		})
+
+		response := <-client.References(ctx, file, 20, 5)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(ConsistOf(volumeCreate.Location()))
+	})
+
+	It("should return the hover of a function", func() {
+		hover := Hover{
+			Contents: MarkupContent{Kind: "markdown", Value: "func (c *RestClient) VolumeGet() error"},
+			Range:    &volumeGet.Item.SelectionRange,
+		}
+		server.Handle("textDocument/hover", func(params json.RawMessage) (interface{}, *ResponseError) {
+			return hover, nil
+		})
+
+		response := <-client.Hover(ctx, file, 20, 5)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(Equal(&hover))
+	})
+
+	It("should return the symbols of a document", func() {
+		symbols := []DocumentSymbolResult{
+			{Name: "VolumeCreate", Kind: SymbolKindMethod, Location: volumeCreate.Location(), ContainerName: "RestClient"},
+			{Name: "VolumeGet", Kind: SymbolKindMethod, Location: volumeGet.Location(), ContainerName: "RestClient"},
+		}
+		server.Handle("textDocument/documentSymbol", func(params json.RawMessage) (interface{}, *ResponseError) {
+			var documentSymbolParams DocumentSymbolParams
+			Expect(json.Unmarshal(params, &documentSymbolParams)).To(Succeed())
+			Expect(documentSymbolParams.TextDocument.Uri).To(Equal("file://" + file))
+			return symbols, nil
+		})
+
+		response := <-client.DocumentSymbol(ctx, file)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(Equal(symbols))
+	})
+
+	It("should return the implementations of an interface method", func() {
+		response := <-client.Implementations(ctx, file, 30, 2)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(ConsistOf(volumeGet.Location()))
+	})
+	It("should call any method, decoding its result into the given type", func() {
+		server.Handle("textDocument/foldingRange", func(params json.RawMessage) (interface{}, *ResponseError) {
+			return []map[string]int{{"startLine": 10, "endLine": 20}}, nil
+		})
+
+		type foldingRange struct {
+			StartLine int `json:"startLine"`
+			EndLine   int `json:"endLine"`
+		}
+		foldingRanges, err := lsp.Call[[]foldingRange](ctx, client, "textDocument/foldingRange",
+			DocumentSymbolParams{TextDocument: TextDocumentIdentifier{Uri: "file://" + file}})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(foldingRanges).To(ConsistOf(foldingRange{StartLine: 10, EndLine: 20}))
+
+		_, err = lsp.Call[interface{}](ctx, client, "workspace/unknown", struct{}{})
+		var responseError *ResponseError
+		Expect(errors.As(err, &responseError)).To(BeTrue())
+		Expect(responseError.Code).To(Equal(MethodNotFound))
+	})
+
+	It("should notify any method", func() {
+		Expect(lsp.Notify(ctx, client, "textDocument/didClose", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{Uri: "file://" + file}})).To(Succeed())
+		Eventually(func() []lsptest.Message {
+			return server.ReceivedMethod("textDocument/didClose")
+		}).Should(HaveLen(1))
+		Expect(server.ReceivedMethod("textDocument/didClose")[0].ID).To(BeNil())
+	})
+
+	It("should fail to notify before the session is started, rather than block", func() {
+		idleServer := lsptest.NewServer()
+		defer idleServer.Close()
+		idleClient := lsp.NewLsp(ctx, idleServer.Conn(), workDir, lsp.Config{})
+
+		err := lsp.Notify(ctx, idleClient, "textDocument/didClose", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{Uri: "file://" + file}})
+		Expect(err).To(MatchError(ContainSubstring("the session isn't started")))
+		Expect(idleServer.ReceivedMethod("textDocument/didClose")).To(BeEmpty())
+	})
+
+	Context("when the server doesn't answer in time", func() {
+		var started, unblock chan struct{}
+
+		// cancelOnceStarted cancels the context as soon as the server got the request.
+		cancelOnceStarted := func() context.Context {
+			cancelCtx, cancel := context.WithCancel(ctx)
+			started := started
+			go func() {
+				<-started
+				cancel()
+			}()
+			return cancelCtx
+		}
+
+		BeforeEach(func() {
+			started = make(chan struct{}, 1)
+			unblock = make(chan struct{})
+			started, unblock := started, unblock
+			server.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *ResponseError) {
+				started <- struct{}{}
+				<-unblock
+				return []Location{volumeGet.Location()}, nil
+			})
+		})
+
+		AfterEach(func() {
+			close(unblock)
+		})
+
+		It("should cancel the request once the context is done", func() {
+			response := <-client.Implementations(cancelOnceStarted(), file, 30, 2)
+			Expect(response.Error).ToNot(BeNil())
+			Expect(response.Error.Code).To(Equal(RequestCancelled))
+
+			implementation := server.ReceivedMethod("textDocument/implementation")
+			Expect(implementation).To(HaveLen(1))
+			Eventually(func() []lsptest.Message {
+				return server.ReceivedMethod("$/cancelRequest")
+			}).Should(HaveLen(1))
+
+			var params CancelParams
+			Expect(json.Unmarshal(server.ReceivedMethod("$/cancelRequest")[0].Params, &params)).To(Succeed())
+			Expect(params.ID).To(Equal(*implementation[0].ID))
+		})
+
+		It("should still answer the requests sent after a cancelled one", func() {
+			response := <-client.Implementations(cancelOnceStarted(), file, 30, 2)
+			Expect(response.Error.Code).To(Equal(RequestCancelled))
+
+			outgoingCalls := <-client.OutgoingCalls(ctx, file, 10, 5)
+			Expect(outgoingCalls.Error).To(BeNil())
+			Expect(outgoingCalls.Result).To(HaveLen(1))
+		})
+	})
+
+	It("should cancel the requests unanswered within the configured timeout", func() {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		timeoutServer := lsptest.NewServer()
+		defer timeoutServer.Close()
+		timeoutServer.Handle("textDocument/hover", func(params json.RawMessage) (interface{}, *ResponseError) {
+			<-unblock
+			return nil, nil
+		})
+
+		timeoutClient := lsp.NewLsp(ctx, timeoutServer.Conn(), workDir, lsp.Config{RequestTimeout: 50 * time.Millisecond})
+		Expect(timeoutClient.Initialize(ctx, "trident")).To(Succeed())
+
+		response := <-timeoutClient.Hover(ctx, file, 20, 5)
+		Expect(response.Error).ToNot(BeNil())
+		Expect(response.Error.Code).To(Equal(RequestCancelled))
+	})
+	It("should keep the configured number of requests in flight, holding back the others", func() {
+		var (
+			inFlight, maxInFlight int
+			inFlightMU            sync.Mutex
+		)
+		current := func() int {
+			inFlightMU.Lock()
+			defer inFlightMU.Unlock()
+			return inFlight
+		}
+
+		// The server holds every request until released, so that the client fills up its limit.
+		release := make(chan struct{})
+		limitedServer := lsptest.NewServer()
+		defer limitedServer.Close()
+		limitedServer.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *ResponseError) {
+			inFlightMU.Lock()
+			inFlight++
+			maxInFlight = max(maxInFlight, inFlight)
+			inFlightMU.Unlock()
+
+			<-release
+
+			inFlightMU.Lock()
+			inFlight--
+			inFlightMU.Unlock()
+			return []Location{volumeGet.Location()}, nil
+		})
+
+		limitedClient := lsp.NewLsp(ctx, limitedServer.Conn(), workDir, lsp.Config{Concurrency: 3})
+		Expect(limitedClient.Initialize(ctx, "trident")).To(Succeed())
+
+		wg := new(sync.WaitGroup)
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				response := <-limitedClient.Implementations(ctx, file, 30, 2)
+				Expect(response.Error).To(BeNil())
+			}()
+		}
+
+		Eventually(current).Should(Equal(3))
+		Consistently(func() int {
+			return len(limitedServer.ReceivedMethod("textDocument/implementation"))
+		}, 100*time.Millisecond).Should(Equal(3))
+
+		close(release)
+		wg.Wait()
+
+		Expect(limitedServer.ReceivedMethod("textDocument/implementation")).To(HaveLen(10))
+		Expect(maxInFlight).To(Equal(3))
+	})
+	It("should keep the diagnostics published by the server", func() {
+		diagnostic := Diagnostic{
+			Range:    Range{Start: Position{Line: 10, Character: 2}},
+			Severity: DiagnosticSeverityError,
+			Message:  "undefined: volumeCreate",
+		}
+		Expect(server.Notify("textDocument/publishDiagnostics",
+			PublishDiagnosticsParams{Uri: "file://" + file, Diagnostics: []Diagnostic{diagnostic}})).To(Succeed())
+		Eventually(client.Diagnostics).Should(HaveKeyWithValue(file, ConsistOf(diagnostic)))
+
+		// An empty publication clears the diagnostics of the file.
+		Expect(server.Notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{Uri: "file://" + file})).To(Succeed())
+		Eventually(client.Diagnostics).ShouldNot(HaveKey(file))
+	})
+
+	It("should acknowledge the work done progress created by the server", func() {
+		Expect(server.Call(1, "window/workDoneProgress/create", map[string]interface{}{"token": "loading"})).To(Succeed())
+		Eventually(func() *lsptest.Message { return server.Reply(1) }).ShouldNot(BeNil())
+		Expect(server.Reply(1).Error).To(BeNil())
+	})
+	Context("while gopls loads the workspace", func() {
+		var loadingServer *lsptest.Server
+
+		progress := func(token string, kind string) {
+			Expect(loadingServer.Notify("$/progress", ProgressParams[WorkDoneProgressValue]{
+				Token: token,
+				Value: WorkDoneProgressValue{Kind: kind, Title: token},
+			})).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			loadingServer = lsptest.NewServer()
+			loadingServer.Handle("initialized", func(params json.RawMessage) (interface{}, *ResponseError) {
+				progress("setup", WorkDoneProgressBegin)
+				progress("packages", WorkDoneProgressBegin)
+				progress("setup", WorkDoneProgressEnd)
+				return nil, nil
+			})
+		})
+
+		AfterEach(func() {
+			Expect(loadingServer.Close()).To(Succeed())
+		})
+
+		It("should wait for every progress begun to end", func() {
+			loadingClient := lsp.NewLsp(ctx, loadingServer.Conn(), workDir, lsp.Config{})
+			initialized := make(chan error, 1)
+			go func() {
+				initialized <- loadingClient.Initialize(ctx, "trident")
+			}()
+
+			Consistently(initialized).ShouldNot(Receive())
+			progress("packages", WorkDoneProgressReport)
+			Consistently(initialized).ShouldNot(Receive())
+			progress("packages", WorkDoneProgressEnd)
+			Eventually(initialized).Should(Receive(BeNil()))
+		})
+
+		It("should wait for the next progress gopls begins right after ending one", func() {
+			loadingClient := lsp.NewLsp(ctx, loadingServer.Conn(), workDir, lsp.Config{ReadyQuietPeriod: 200 * time.Millisecond})
+			initialized := make(chan error, 1)
+			go func() {
+				initialized <- loadingClient.Initialize(ctx, "trident")
+			}()
+
+			Eventually(loadingServer.ReceivedMethod).WithArguments("initialized").Should(HaveLen(1))
+			progress("packages", WorkDoneProgressEnd)
+			progress("diagnostics", WorkDoneProgressBegin)
+			Consistently(initialized, 300*time.Millisecond).ShouldNot(Receive())
+			progress("diagnostics", WorkDoneProgressEnd)
+			Eventually(initialized).Should(Receive(BeNil()))
+		})
+
+		It("should take the workspace as loaded if gopls reports no progress", func() {
+			silentServer := lsptest.NewServer()
+			defer silentServer.Close()
+			silentServer.Handle("initialized", func(params json.RawMessage) (interface{}, *ResponseError) {
+				return nil, nil
+			})
+
+			silentClient := lsp.NewLsp(ctx, silentServer.Conn(), workDir, lsp.Config{ReadyIdleTimeout: 50 * time.Millisecond})
+			Expect(silentClient.Initialize(ctx, "trident")).To(Succeed())
+		})
+
+		It("should give up once the ready timeout is over", func() {
+			loadingClient := lsp.NewLsp(ctx, loadingServer.Conn(), workDir, lsp.Config{ReadyTimeout: 50 * time.Millisecond})
+			err := loadingClient.Initialize(ctx, "trident")
+			Expect(err).To(MatchError(ContainSubstring("workspace not loaded within 50ms")))
+		})
+	})
+
+	It("should hand the gopls settings over at initialization, and whenever asked for", func() {
+		expandWorkspaceToModule := false
+		settings := GoplsSettings{
+			BuildFlags:              []string{"-tags=e2e"},
+			Env:                     map[string]string{"GOFLAGS": "-mod=vendor"},
+			DirectoryFilters:        []string{"-vendor", "-**/mocks"},
+			ExpandWorkspaceToModule: &expandWorkspaceToModule,
+		}
+		settingsJSON, err := json.Marshal(settings)
+		Expect(err).ToNot(HaveOccurred())
+
+		settingsServer := lsptest.NewServer()
+		defer settingsServer.Close()
+		settingsClient := lsp.NewLsp(ctx, settingsServer.Conn(), workDir, lsp.Config{Settings: settings})
+		Expect(settingsClient.Initialize(ctx, "trident")).To(Succeed())
+
+		var params InitializeParams
+		Expect(json.Unmarshal(settingsServer.ReceivedMethod("initialize")[0].Params, &params)).To(Succeed())
+		initializationOptions, err := json.Marshal(params.InitializationOptions)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(initializationOptions).To(MatchJSON(settingsJSON))
+		// Otherwise gopls never asks for them.
+		Expect(params.Capabilities.Workspace).ToNot(BeNil())
+		Expect(params.Capabilities.Workspace.Configuration).To(BeTrue())
+
+		Eventually(func() []lsptest.Message {
+			return settingsServer.ReceivedMethod("workspace/didChangeConfiguration")
+		}).Should(HaveLen(1))
+		Expect(settingsServer.ReceivedMethod("workspace/didChangeConfiguration")[0].Params).To(MatchJSON(`{"settings": {"gopls": ` + string(settingsJSON) + `}}`))
+
+		Expect(settingsServer.Call(1, "workspace/configuration", ConfigurationParams{
+			Items: []ConfigurationItem{{Section: "gopls"}, {Section: "go"}},
+		})).To(Succeed())
+		Eventually(func() *lsptest.Message { return settingsServer.Reply(1) }).ShouldNot(BeNil())
+		Expect(settingsServer.Reply(1).Result).To(MatchJSON(`[` + string(settingsJSON) + `, null]`))
+	})
+
+	It("should leave gopls to its defaults if no settings are given", func() {
+		Expect(server.ReceivedMethod("workspace/didChangeConfiguration")).To(BeEmpty())
+
+		Expect(server.Call(1, "workspace/configuration", ConfigurationParams{Items: []ConfigurationItem{{Section: "gopls"}}})).To(Succeed())
+		Eventually(func() *lsptest.Message { return server.Reply(1) }).ShouldNot(BeNil())
+		Expect(server.Reply(1).Result).To(MatchJSON(`[null]`))
+	})
+
+	It("should open the overlaid files, with their overlaid content", func() {
+		goMod, volumes := filepath.Join(workDir, "go.mod"), filepath.Join(workDir, "volumes.go")
+		overlayServer := lsptest.NewServer()
+		defer overlayServer.Close()
+		overlayClient := lsp.NewLsp(ctx, overlayServer.Conn(), workDir, lsp.Config{Overlay: overlay.Overlay{
+			volumes: []byte("package api\n\nfunc VolumeModify() {}\n"),
+			goMod:   []byte("module github.com/netapp/trident\n"),
+		}})
+		Expect(overlayClient.Initialize(ctx, "trident")).To(Succeed())
+
+		Eventually(func() []lsptest.Message {
+			return overlayServer.ReceivedMethod("textDocument/didOpen")
+		}).Should(HaveLen(2))
+		opened := overlayServer.ReceivedMethod("textDocument/didOpen")
+		Expect(opened[0].Params).To(MatchJSON(`{"textDocument": {"uri": "file://` + goMod +
+			`", "languageId": "go.mod", "version": 1, "text": "module github.com/netapp/trident\n"}}`))
+		Expect(opened[1].Params).To(MatchJSON(`{"textDocument": {"uri": "file://` + volumes +
+			`", "languageId": "go", "version": 1, "text": "package api\n\nfunc VolumeModify() {}\n"}}`))
+	})
+
+	It("should shut the server down, then tell it to exit", func() {
+		Expect(client.Shutdown(ctx)).To(Succeed())
+		Expect(server.ReceivedMethod("shutdown")).To(HaveLen(1))
+		Eventually(func() []lsptest.Message {
+			return server.ReceivedMethod("exit")
+		}).Should(HaveLen(1))
+		Expect(server.ReceivedMethod("exit")[0].ID).To(BeNil())
+	})
+
+	Context("when the connection drops", func() {
+		var droppingServer, nextServer *lsptest.Server
+		var started, unblock chan struct{}
+
+		BeforeEach(func() {
+			started = make(chan struct{}, 1)
+			unblock = make(chan struct{})
+			started, unblock := started, unblock
+			droppingServer = lsptest.NewServer()
+			droppingServer.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *ResponseError) {
+				started <- struct{}{}
+				<-unblock
+				return nil, nil
+			})
+
+			nextServer = lsptest.NewServer()
+			nextServer.Script(iface)
+		})
+
+		AfterEach(func() {
+			close(unblock)
+			Expect(droppingServer.Close()).To(Succeed())
+			Expect(nextServer.Close()).To(Succeed())
+		})
+
+		// dropOnceStarted closes the connection as soon as the server got the request.
+		dropOnceStarted := func() {
+			started, droppingServer := started, droppingServer
+			go func() {
+				<-started
+				_ = droppingServer.Close()
+			}()
+		}
+
+		It("should reconnect and send the requests in flight again", func() {
+			droppingClient := lsp.NewLsp(ctx, droppingServer.Conn(), workDir, lsp.Config{
+				Dial:          func(ctx context.Context) (io.ReadWriteCloser, error) { return nextServer.Conn(), nil },
+				MaxReconnects: 1,
+			})
+			Expect(droppingClient.Initialize(ctx, "trident")).To(Succeed())
+
+			dropOnceStarted()
+			response := <-droppingClient.Implementations(ctx, file, 30, 2)
+			Expect(response.Error).To(BeNil())
+			Expect(response.Result).To(ConsistOf(volumeGet.Location()))
+
+			// The new session is initialized before the request is sent again.
+			Expect(nextServer.ReceivedMethod("initialize")).To(HaveLen(1))
+			Expect(nextServer.ReceivedMethod("initialized")).To(HaveLen(1))
+			Expect(nextServer.ReceivedMethod("textDocument/implementation")).To(HaveLen(1))
+		})
+
+		It("should fail the requests in flight if it can't reconnect", func() {
+			droppingClient := lsp.NewLsp(ctx, droppingServer.Conn(), workDir, lsp.Config{})
+			Expect(droppingClient.Initialize(ctx, "trident")).To(Succeed())
+
+			dropOnceStarted()
+			response := <-droppingClient.Implementations(ctx, file, 30, 2)
+			Expect(response.Error).ToNot(BeNil())
+			Expect(response.Error.Code).To(Equal(ConnectionClosed))
+		})
+
+		It("should not reconnect once shut down", func() {
+			dialed := false
+			droppingClient := lsp.NewLsp(ctx, droppingServer.Conn(), workDir, lsp.Config{
+				Dial: func(ctx context.Context) (io.ReadWriteCloser, error) {
+					dialed = true
+					return nextServer.Conn(), nil
+				},
+				MaxReconnects: 1,
+			})
+			Expect(droppingClient.Initialize(ctx, "trident")).To(Succeed())
+			Expect(droppingClient.Shutdown(ctx)).To(Succeed())
+
+			response := <-droppingClient.Implementations(ctx, file, 30, 2)
+			Expect(response.Error).ToNot(BeNil())
+			Expect(response.Error.Code).To(Equal(ConnectionClosed))
+			Expect(dialed).To(BeFalse())
+		})
+	})
+})
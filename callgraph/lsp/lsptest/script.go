@@ -0,0 +1,117 @@
+package lsptest
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+// Function is one row of the scripted call-graph served by Script.
+type Function struct {
+	// Item is what prepareCallHierarchy answers for any position within Item.SelectionRange.
+	Item requests.CallHierarchyItem
+	// Calls is what callHierarchy/outgoingCalls answers for Item.
+	Calls []requests.CallHierarchyItem
+	// Implementations is what textDocument/implementation answers for any position within Item.SelectionRange.
+	Implementations []requests.Location
+}
+
+// NewFunction returns a Function whose Item is named name and selected at the given zero based position.
+func NewFunction(filePath string, line, character int, name, detail string) Function {
+	selection := requests.Range{
+		Start: requests.Position{Line: line, Character: character},
+		End:   requests.Position{Line: line, Character: character + len(name)},
+	}
+	return Function{
+		Item: requests.CallHierarchyItem{
+			Name:           name,
+			Kind:           requests.SymbolKindFunction,
+			Detail:         detail,
+			Uri:            fmt.Sprintf("file://%s", filePath),
+			Range:          selection,
+			SelectionRange: selection,
+		},
+	}
+}
+
+// Location returns where the function is, as textDocument/implementation would answer it.
+func (f Function) Location() requests.Location {
+	return requests.Location{Uri: f.Item.Uri, Range: f.Item.SelectionRange}
+}
+
+// Script makes the server answer textDocument/prepareCallHierarchy, callHierarchy/outgoingCalls,
+// callHierarchy/incomingCalls and textDocument/implementation from the given table.
+// Incoming calls are derived from the Calls of every function. Positions not in the table are answered with an empty result.
+func (s *Server) Script(functions ...Function) {
+	find := func(params json.RawMessage) (*Function, *requests.ResponseError) {
+		var position requests.TextDocumentPositionParams
+		if err := json.Unmarshal(params, &position); err != nil {
+			return nil, &requests.ResponseError{Code: requests.InvalidParams, Message: err.Error()}
+		}
+		for i := range functions {
+			if functions[i].Item.Uri == position.TextDocument.Uri && contains(functions[i].Item.SelectionRange, position.Position) {
+				return &functions[i], nil
+			}
+		}
+		return nil, nil
+	}
+
+	s.Handle("textDocument/prepareCallHierarchy", func(params json.RawMessage) (interface{}, *requests.ResponseError) {
+		function, responseError := find(params)
+		if function == nil {
+			return []requests.CallHierarchyItem{}, responseError
+		}
+		return []requests.CallHierarchyItem{function.Item}, nil
+	})
+
+	s.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *requests.ResponseError) {
+		function, responseError := find(params)
+		if function == nil {
+			return []requests.Location{}, responseError
+		}
+		return append([]requests.Location{}, function.Implementations...), nil
+	})
+
+	s.Handle("callHierarchy/outgoingCalls", func(params json.RawMessage) (interface{}, *requests.ResponseError) {
+		var outgoingCallsParams requests.CallHierarchyOutgoingCallsParams
+		if err := json.Unmarshal(params, &outgoingCallsParams); err != nil {
+			return nil, &requests.ResponseError{Code: requests.InvalidParams, Message: err.Error()}
+		}
+
+		calls := make([]requests.CallHierarchyOutgoingCall, 0)
+		for _, function := range functions {
+			if function.Item.Uri != outgoingCallsParams.Item.Uri || function.Item.Range != outgoingCallsParams.Item.Range {
+				continue
+			}
+			for _, callee := range function.Calls {
+				calls = append(calls, requests.CallHierarchyOutgoingCall{To: callee})
+			}
+		}
+		return calls, nil
+	})
+
+	s.Handle("callHierarchy/incomingCalls", func(params json.RawMessage) (interface{}, *requests.ResponseError) {
+		var incomingCallsParams requests.CallHierarchyIncomingCallsParams
+		if err := json.Unmarshal(params, &incomingCallsParams); err != nil {
+			return nil, &requests.ResponseError{Code: requests.InvalidParams, Message: err.Error()}
+		}
+
+		calls := make([]requests.CallHierarchyIncomingCall, 0)
+		for _, function := range functions {
+			for _, callee := range function.Calls {
+				if callee.Uri == incomingCallsParams.Item.Uri && callee.Range == incomingCallsParams.Item.Range {
+					calls = append(calls, requests.CallHierarchyIncomingCall{From: function.Item})
+					break
+				}
+			}
+		}
+		return calls, nil
+	})
+}
+
+func contains(r requests.Range, position requests.Position) bool {
+	return position.Line >= r.Start.Line && position.Line <= r.End.Line &&
+		(position.Line != r.Start.Line || position.Character >= r.Start.Character) &&
+		(position.Line != r.End.Line || position.Character <= r.End.Character)
+}
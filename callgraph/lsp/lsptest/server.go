@@ -0,0 +1,277 @@
+// Package lsptest provides an in-process stand-in for gopls, so that the lsp and requests packages,
+// and everything built on top of them, can be tested without a real language server.
+//
+// The server speaks the same framing as utils.ConstructRequest / utils.FindTheContentLength over
+// an in-memory connection, answers requests from handlers registered per method, and can push
+// notifications or reply out of order on demand.
+package lsptest
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/utils"
+)
+
+// Message is a JSON-RPC message as received by the server.
+type Message struct {
+	Jsonrpc string                  `json:"jsonrpc"`
+	ID      *int                    `json:"id,omitempty"`
+	Method  string                  `json:"method,omitempty"`
+	Params  json.RawMessage         `json:"params,omitempty"`
+	Result  json.RawMessage         `json:"result,omitempty"`
+	Error   *requests.ResponseError `json:"error,omitempty"`
+}
+
+// Handler answers a request, returning either a result or an error.
+// For notifications the returned values are ignored.
+type Handler func(params json.RawMessage) (interface{}, *requests.ResponseError)
+
+type Server struct {
+	conn   net.Conn
+	client net.Conn
+	reader *bufio.Reader
+
+	writeMU *sync.Mutex
+
+	handlers   map[string]Handler
+	handlersMU *sync.Mutex
+
+	received   []Message
+	receivedMU *sync.Mutex
+
+	// Replies are held back until heldCount of them are collected, then sent in reverse order.
+	heldCount   int
+	heldReplies [][]byte
+	heldMU      *sync.Mutex
+
+	closed chan struct{}
+}
+
+// NewServer starts a fake LSP server. It answers initialize with empty capabilities, answers
+// the initialized notification with the $/progress gopls reports while loading the workspace, and acknowledges shutdown.
+func NewServer() *Server {
+	server, client := net.Pipe()
+	s := &Server{
+		conn:       server,
+		client:     client,
+		reader:     bufio.NewReader(server),
+		writeMU:    new(sync.Mutex),
+		handlers:   make(map[string]Handler),
+		handlersMU: new(sync.Mutex),
+		receivedMU: new(sync.Mutex),
+		heldMU:     new(sync.Mutex),
+		closed:     make(chan struct{}),
+	}
+
+	s.Handle("initialize", func(params json.RawMessage) (interface{}, *requests.ResponseError) {
+		return requests.InitializeResult{}, nil
+	})
+	s.Handle("initialized", func(params json.RawMessage) (interface{}, *requests.ResponseError) {
+		s.Load()
+		return nil, nil
+	})
+	s.Handle("shutdown", func(params json.RawMessage) (interface{}, *requests.ResponseError) {
+		return nil, nil
+	})
+
+	go s.serve()
+	return s
+}
+
+// Conn returns the client side of the connection, to be handed over to lsp.NewLsp.
+func (s *Server) Conn() io.ReadWriteCloser {
+	return s.client
+}
+
+// Handle registers the handler of method, replacing the previous one.
+func (s *Server) Handle(method string, handler Handler) {
+	s.handlersMU.Lock()
+	defer s.handlersMU.Unlock()
+	s.handlers[method] = handler
+}
+
+// Notify sends a notification to the client.
+func (s *Server) Notify(method string, params interface{}) error {
+	return s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"method":  method,
+		"params":  params,
+	})
+}
+
+// Load reports the loading of the workspace through $/progress, begun and ended right away.
+func (s *Server) Load() {
+	_ = s.Notify("$/progress", requests.ProgressParams[requests.WorkDoneProgressValue]{
+		Token: "setup",
+		Value: requests.WorkDoneProgressValue{Kind: requests.WorkDoneProgressBegin, Title: "Setting up workspace"},
+	})
+	_ = s.Notify("$/progress", requests.ProgressParams[requests.WorkDoneProgressValue]{
+		Token: "setup",
+		Value: requests.WorkDoneProgressValue{Kind: requests.WorkDoneProgressEnd, Message: "Finished loading packages."},
+	})
+}
+
+// Call sends a request to the client, as gopls does for window/workDoneProgress/create and the like.
+// The reply of the client is then returned by Reply.
+func (s *Server) Call(id int, method string, params interface{}) error {
+	return s.write(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      id,
+		"method":  method,
+		"params":  params,
+	})
+}
+
+// Reply returns the reply of the client to the request sent by Call with the given ID, nil until received.
+func (s *Server) Reply(id int) *Message {
+	for _, message := range s.Received() {
+		if message.Method == "" && message.ID != nil && *message.ID == id {
+			return &message
+		}
+	}
+	return nil
+}
+
+// ReplyOutOfOrder holds back the next count replies and sends them in reverse order
+// once all of them are ready. count must not be more than the requests the client sends concurrently.
+func (s *Server) ReplyOutOfOrder(count int) {
+	s.heldMU.Lock()
+	defer s.heldMU.Unlock()
+	s.heldCount = count
+}
+
+// Received returns every message the server has received so far.
+func (s *Server) Received() []Message {
+	s.receivedMU.Lock()
+	defer s.receivedMU.Unlock()
+	return append([]Message(nil), s.received...)
+}
+
+// ReceivedMethod returns the messages received so far for the given method.
+func (s *Server) ReceivedMethod(method string) []Message {
+	var messages []Message
+	for _, message := range s.Received() {
+		if message.Method == method {
+			messages = append(messages, message)
+		}
+	}
+	return messages
+}
+
+// Close closes both ends of the connection.
+func (s *Server) Close() error {
+	select {
+	case <-s.closed:
+		return nil
+	default:
+		close(s.closed)
+	}
+	_ = s.client.Close()
+	return s.conn.Close()
+}
+
+func (s *Server) serve() {
+	for {
+		contentLength, err := utils.FindTheContentLength(s.reader)
+		if err != nil {
+			return
+		}
+
+		content := make([]byte, contentLength)
+		if _, err = io.ReadFull(s.reader, content); err != nil {
+			return
+		}
+
+		var message Message
+		if err = json.Unmarshal(content, &message); err != nil {
+			continue
+		}
+
+		s.receivedMU.Lock()
+		s.received = append(s.received, message)
+		s.receivedMU.Unlock()
+
+		// Handlers may block or write themselves, never hold the read loop.
+		go s.dispatch(message)
+	}
+}
+
+func (s *Server) dispatch(message Message) {
+	s.handlersMU.Lock()
+	handler, ok := s.handlers[message.Method]
+	s.handlersMU.Unlock()
+
+	if message.Method == "" {
+		// A reply of the client to a request sent by Call.
+		return
+	}
+
+	if message.ID == nil {
+		// A notification, nothing to reply.
+		if ok {
+			handler(message.Params)
+		}
+		return
+	}
+
+	reply := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      *message.ID,
+	}
+	if !ok {
+		reply["error"] = &requests.ResponseError{
+			Code:    requests.MethodNotFound,
+			Message: fmt.Sprintf("method %q is not handled by the fake server", message.Method),
+		}
+	} else if result, responseError := handler(message.Params); responseError != nil {
+		reply["error"] = responseError
+	} else {
+		reply["result"] = result
+	}
+
+	replyJSON, err := json.Marshal(reply)
+	if err != nil {
+		return
+	}
+
+	s.heldMU.Lock()
+	if s.heldCount > 0 {
+		s.heldReplies = append(s.heldReplies, replyJSON)
+		if len(s.heldReplies) < s.heldCount {
+			s.heldMU.Unlock()
+			return
+		}
+
+		held := s.heldReplies
+		s.heldReplies, s.heldCount = nil, 0
+		s.heldMU.Unlock()
+		for i := len(held) - 1; i >= 0; i-- {
+			_ = s.writeRaw(held[i])
+		}
+		return
+	}
+	s.heldMU.Unlock()
+
+	_ = s.writeRaw(replyJSON)
+}
+
+func (s *Server) write(message interface{}) error {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	return s.writeRaw(messageJSON)
+}
+
+func (s *Server) writeRaw(messageJSON []byte) error {
+	s.writeMU.Lock()
+	defer s.writeMU.Unlock()
+	_, err := io.WriteString(s.conn, utils.ConstructRequest(messageJSON))
+	return err
+}
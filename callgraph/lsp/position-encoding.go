@@ -0,0 +1,154 @@
+package lsp
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+// positions converts the characters of the positions between go/token, and so the CallGraph, which count bytes,
+// and the server, which counts UTF-16 code units unless it agreed to count bytes too. It is the only place
+// where they are converted, every position crossing AbstractionLSP goes through it.
+type positions struct {
+	// encoding is the one negotiated with the server, asked every time as reconnecting negotiates it again.
+	encoding func() PositionEncodingKind
+	// readFile reads the files through the overlay, whose positions are those of the overlaid content.
+	readFile func(filePath string) ([]byte, error)
+
+	// lines holds the lines of every file read so far, keyed by file path.
+	lines   map[string][][]byte
+	linesMU *sync.Mutex
+}
+
+func newPositions(encoding func() PositionEncodingKind, readFile func(filePath string) ([]byte, error)) *positions {
+	return &positions{
+		encoding: encoding,
+		readFile: readFile,
+		lines:    make(map[string][][]byte),
+		linesMU:  new(sync.Mutex),
+	}
+}
+
+// toServer converts the byte column on the line of the file into the character the server expects.
+func (p *positions) toServer(filePath string, line, column int) int {
+	text, ok := p.line(filePath, line)
+	if !ok {
+		return column
+	}
+
+	switch p.encoding() {
+	case PositionEncodingUTF8:
+		return column
+	case PositionEncodingUTF32:
+		return utf8.RuneCount(prefix(text, column))
+	default:
+		return utf16Length(prefix(text, column))
+	}
+}
+
+// fromServer converts the character the server sent on the line of the file into a byte column.
+func (p *positions) fromServer(filePath string, line, character int) int {
+	text, ok := p.line(filePath, line)
+	if !ok {
+		return character
+	}
+
+	switch p.encoding() {
+	case PositionEncodingUTF8:
+		return character
+	case PositionEncodingUTF32:
+		return byteColumn(text, character, func(r rune) int { return 1 })
+	default:
+		return byteColumn(text, character, utf16RuneLen)
+	}
+}
+
+func (p *positions) fromServerRange(filePath string, r *Range) {
+	r.Start.Character = p.fromServer(filePath, r.Start.Line, r.Start.Character)
+	r.End.Character = p.fromServer(filePath, r.End.Line, r.End.Character)
+}
+
+func (p *positions) fromServerLocations(locations []Location) {
+	for i := range locations {
+		p.fromServerRange(filePathOf(locations[i].Uri), &locations[i].Range)
+	}
+}
+
+func (p *positions) fromServerItem(item *CallHierarchyItem) {
+	p.fromServerRange(filePathOf(item.Uri), &item.Range)
+	p.fromServerRange(filePathOf(item.Uri), &item.SelectionRange)
+}
+
+func filePathOf(uri string) string {
+	return strings.TrimPrefix(uri, "file://")
+}
+
+// line returns the line of the file, false if there is no such line or if it is ASCII, counting the same
+// in every encoding.
+func (p *positions) line(filePath string, line int) ([]byte, bool) {
+	if p.encoding() == PositionEncodingUTF8 {
+		return nil, false
+	}
+
+	p.linesMU.Lock()
+	lines, ok := p.lines[filePath]
+	if !ok {
+		// A file which can't be read is left as if ASCII, the positions are then sent as they are.
+		content, _ := p.readFile(filePath)
+		lines = bytes.Split(content, []byte("\n"))
+		p.lines[filePath] = lines
+	}
+	p.linesMU.Unlock()
+
+	if line < 0 || line >= len(lines) || isASCII(lines[line]) {
+		return nil, false
+	}
+	return lines[line], true
+}
+
+func prefix(text []byte, column int) []byte {
+	return text[:max(0, min(column, len(text)))]
+}
+
+func utf16Length(text []byte) int {
+	length := 0
+	for len(text) > 0 {
+		r, size := utf8.DecodeRune(text)
+		length += utf16RuneLen(r)
+		text = text[size:]
+	}
+	return length
+}
+
+// byteColumn returns the byte column of the character, counting each rune as runeLen tells.
+// A character past the end of the line is the end of the line, as LSP defines it.
+func byteColumn(text []byte, character int, runeLen func(rune) int) int {
+	column := 0
+	for column < len(text) && character > 0 {
+		r, size := utf8.DecodeRune(text[column:])
+		character -= runeLen(r)
+		column += size
+	}
+	return column
+}
+
+// utf16RuneLen is how many UTF-16 code units encode the rune, those past the Basic Multilingual Plane
+// taking a surrogate pair.
+func utf16RuneLen(r rune) int {
+	if r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+func isASCII(text []byte) bool {
+	for _, b := range text {
+		if b >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,110 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/overlay"
+)
+
+var _ = Describe("Position encoding", func() {
+	const (
+		// The comment before the name takes 22 bytes, but 16 UTF-16 code units, the emoji taking a surrogate pair.
+		source      = "package api\n\nfunc /* 容量😀 */ VolumeCreate() {}\n"
+		line        = 2
+		byteColumn  = 22
+		utf16Column = 16
+	)
+
+	var (
+		ctx     = context.Background()
+		workDir string
+		file    string
+		server  *lsptest.Server
+	)
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "position-encoding")
+		Expect(err).ToNot(HaveOccurred())
+		file = filepath.Join(workDir, "ontap_rest.go")
+		Expect(os.WriteFile(file, []byte(source), 0o644)).To(Succeed())
+
+		server = lsptest.NewServer()
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	// script answers the implementation of the function with itself, at the column the server counts.
+	script := func(column int) {
+		volumeCreate := lsptest.NewFunction(file, line, column, "VolumeCreate", "")
+		volumeCreate.Implementations = []Location{volumeCreate.Location()}
+		server.Script(volumeCreate)
+	}
+
+	initializeWith := func(config lsp.Config) *lsp.AbstractionLSP {
+		client := lsp.NewAbstractionLSP(ctx, server.Conn(), workDir, "trident", config)
+		Expect(client.Initialize(ctx)).To(Succeed())
+		return client
+	}
+
+	initialize := func() *lsp.AbstractionLSP {
+		return initializeWith(lsp.Config{})
+	}
+
+	It("should offer to count bytes, falling back on UTF-16", func() {
+		initialize()
+
+		var params InitializeParams
+		Expect(json.Unmarshal(server.ReceivedMethod("initialize")[0].Params, &params)).To(Succeed())
+		Expect(params.Capabilities.General.PositionEncodings).To(Equal(
+			[]PositionEncodingKind{PositionEncodingUTF8, PositionEncodingUTF16}))
+	})
+
+	It("should convert the byte columns to UTF-16 and back, unless negotiated otherwise", func() {
+		script(utf16Column)
+		client := initialize()
+
+		// The last letter of the name, which as a UTF-16 column would be past it.
+		response := <-client.Implementations(ctx, file, line, byteColumn+len("VolumeCreate")-1)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+		Expect(response.Result[0].Range.Start.Character).To(Equal(byteColumn))
+		Expect(response.Result[0].Range.End.Character).To(Equal(byteColumn + len("VolumeCreate")))
+	})
+
+	It("should send the byte columns as they are once the server agreed to count bytes", func() {
+		server.Handle("initialize", func(params json.RawMessage) (interface{}, *ResponseError) {
+			return InitializeResult{Capabilities: ServerCapabilities{PositionEncoding: PositionEncodingUTF8}}, nil
+		})
+		script(byteColumn)
+		client := initialize()
+
+		response := <-client.Implementations(ctx, file, line, byteColumn+1)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+		Expect(response.Result[0].Range.Start.Character).To(Equal(byteColumn))
+	})
+
+	It("should count the characters of the overlaid content, rather than of the file on disk", func() {
+		Expect(os.WriteFile(file, []byte("package api\n"), 0o644)).To(Succeed())
+		script(utf16Column)
+		client := initializeWith(lsp.Config{Overlay: overlay.Overlay{file: []byte(source)}})
+
+		response := <-client.Implementations(ctx, file, line, byteColumn+len("VolumeCreate")-1)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+		Expect(response.Result[0].Range.Start.Character).To(Equal(byteColumn))
+	})
+})
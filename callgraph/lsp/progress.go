@@ -0,0 +1,100 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	. "github.com/theshashankpal/api-collector/logger"
+)
+
+// progress tracks the work done progress gopls reports through $/progress. The workspace is loaded once some
+// progress has begun, and no progress has been active for the quiet period, as gopls ends a progress before
+// beginning the next one.
+type progress struct {
+	// active holds the title of every progress begun and not ended yet, keyed by token.
+	active   map[string]string
+	begun    bool
+	activeMU *sync.Mutex
+
+	quiet time.Duration
+	// quieted counts the times every progress ended, the quiet period only ending the latest.
+	quieted int
+
+	ready     chan struct{}
+	readyOnce *sync.Once
+}
+
+func newProgress(quiet time.Duration) *progress {
+	return &progress{
+		active:    make(map[string]string),
+		activeMU:  new(sync.Mutex),
+		quiet:     quiet,
+		ready:     make(chan struct{}),
+		readyOnce: new(sync.Once),
+	}
+}
+
+// awaitBegin takes the workspace as loaded if no progress has begun within idle, gopls then reporting none.
+// The returned timer is stopped once done waiting.
+func (p *progress) awaitBegin(ctx context.Context, idle time.Duration) *time.Timer {
+	return time.AfterFunc(idle, func() {
+		p.activeMU.Lock()
+		defer p.activeMU.Unlock()
+
+		if !p.begun {
+			Log(ctx, shf).Warn().Msgf("No progress reported within %s, taking the workspace as loaded", idle)
+			p.readyOnce.Do(func() { close(p.ready) })
+		}
+	})
+}
+
+// handle is the handler of the $/progress notifications.
+func (p *progress) handle(ctx context.Context) NotificationHandler {
+	return func(params json.RawMessage) {
+		var progressParams ProgressParams[WorkDoneProgressValue]
+		if err := json.Unmarshal(params, &progressParams); err != nil {
+			return
+		}
+
+		// Tokens are either numbers or strings.
+		token := fmt.Sprint(progressParams.Token)
+		value := progressParams.Value
+
+		p.activeMU.Lock()
+		defer p.activeMU.Unlock()
+
+		switch value.Kind {
+		case WorkDoneProgressBegin:
+			p.begun = true
+			// Ends the quiet period, if any.
+			p.quieted++
+			p.active[token] = value.Title
+			Log(ctx, shf).Info().Str("token", token).Str("message", value.Message).Msgf("%s...", value.Title)
+		case WorkDoneProgressReport:
+			event := Log(ctx, shf).Debug().Str("token", token).Str("message", value.Message)
+			if value.Percentage != nil {
+				event = event.Int("percentage", *value.Percentage)
+			}
+			event.Msg(p.active[token])
+		case WorkDoneProgressEnd:
+			Log(ctx, shf).Info().Str("token", token).Str("message", value.Message).Msgf("%s done", p.active[token])
+			delete(p.active, token)
+			if len(p.active) == 0 {
+				p.quieted++
+				quieted := p.quieted
+				time.AfterFunc(p.quiet, func() {
+					p.activeMU.Lock()
+					defer p.activeMU.Unlock()
+
+					if quieted == p.quieted && len(p.active) == 0 {
+						p.readyOnce.Do(func() { close(p.ready) })
+					}
+				})
+			}
+		}
+	}
+}
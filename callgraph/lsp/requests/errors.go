@@ -80,6 +80,7 @@ const (
 	JsonMarshalError                 = -32106
 	JsonUnMarshalError               = -32107
 	EmptyCallHierarchPrepareResponse = -32108
+	ConnectionClosed                 = -32109
 )
 
 type ResponseError struct {
@@ -0,0 +1,39 @@
+package requests
+
+// GoplsSettings are the gopls settings the analysis depends on, sent as initialization options and answered to
+// the workspace/configuration requests of gopls. See https://github.com/golang/tools/blob/master/gopls/doc/settings.md.
+type GoplsSettings struct {
+	/**
+	 * Flags passed to the build system when loading the packages, e.g. `-tags=e2e`.
+	 */
+	BuildFlags []string `json:"buildFlags,omitempty"`
+
+	/**
+	 * Environment variables added to the build system invocations, e.g. `GOFLAGS`.
+	 */
+	Env map[string]string `json:"env,omitempty"`
+
+	/**
+	 * Directories to include or exclude from the workspace, e.g. `-vendor`. A `**` path component matches
+	 * any number of directories.
+	 */
+	DirectoryFilters []string `json:"directoryFilters,omitempty"`
+
+	/**
+	 * Whether the workspace covers the whole modules of the workspace folders, rather than the folders only.
+	 * Left to gopls if not set.
+	 */
+	ExpandWorkspaceToModule *bool `json:"expandWorkspaceToModule,omitempty"`
+}
+
+// IsZero tells whether nothing is set, gopls then sticking to its defaults.
+func (s GoplsSettings) IsZero() bool {
+	return len(s.BuildFlags) == 0 && len(s.Env) == 0 && len(s.DirectoryFilters) == 0 && s.ExpandWorkspaceToModule == nil
+}
+
+type DidChangeConfigurationParams struct {
+	/**
+	 * The actual changed settings, keyed by section.
+	 */
+	Settings interface{} `json:"settings"`
+}
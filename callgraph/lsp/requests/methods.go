@@ -0,0 +1,270 @@
+package requests
+
+// The params and the results of the methods sent to the server, each answered with a GenericResponse of its result.
+
+// textDocument/prepareCallHierarchy
+
+type CallHierarchyPrepareParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+}
+
+type CallHierarchyItem struct {
+	/**
+	 * The name of this item.
+	 */
+	Name string `json:"name"`
+
+	/**
+	 * The kind of this item.
+	 */
+	Kind SymbolKind `json:"kind"`
+
+	/**
+	 * More detail for this item, e.g. the signature of a function.
+	 */
+	Detail string `json:"detail"`
+
+	/**
+	 * The resource identifier of this item.
+	 */
+	Uri string `json:"uri"`
+
+	/**
+	 * The range enclosing this symbol not including leading/trailing whitespace
+	 * but everything else, e.g. comments and code.
+	 */
+	Range Range `json:"range"`
+
+	/**
+	 * The range that should be selected and revealed when this symbol is being
+	 * picked, e.g. the name of a function. Must be contained by the
+	 * [`range`](#CallHierarchyItem.range).
+	 */
+	SelectionRange Range `json:"selectionRange"`
+}
+
+type CallHierarchyPrepareResponse = GenericResponse[[]CallHierarchyItem]
+
+// callHierarchy/outgoingCalls
+
+type CallHierarchyOutgoingCallsParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+	Item CallHierarchyItem `json:"item"`
+}
+
+type CallHierarchyOutgoingCall struct {
+
+	/**
+	 * The item that is called.
+	 */
+	To CallHierarchyItem `json:"to"`
+
+	/**
+	 * The range at which this item is called. This is the range relative to
+	 * the caller, e.g the item passed to `callHierarchy/outgoingCalls` request.
+	 */
+	FromRanges []Range `json:"fromRanges"`
+}
+
+type CallHierarchyOutgoingCallResponse = GenericResponse[[]CallHierarchyOutgoingCall]
+
+// callHierarchy/incomingCalls
+
+type CallHierarchyIncomingCallsParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+	Item CallHierarchyItem `json:"item"`
+}
+
+type CallHierarchyIncomingCall struct {
+
+	/**
+	 * The item that makes the call.
+	 */
+	From CallHierarchyItem `json:"from"`
+
+	/**
+	 * The ranges at which the calls appear. This is relative to the caller
+	 * denoted by [`this.from`](#CallHierarchyIncomingCall.from).
+	 */
+	FromRanges []Range `json:"fromRanges"`
+}
+
+type CallHierarchyIncomingCallResponse = GenericResponse[[]CallHierarchyIncomingCall]
+
+// textDocument/implementation
+
+type ImplementationParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type ImplementationResponse = GenericResponse[[]Location]
+
+// textDocument/references
+
+type ReferencesParams struct {
+	TextDocumentPositionParams
+	Context ReferenceContext `json:"context"`
+}
+
+type ReferenceResponse = GenericResponse[[]Location]
+
+// textDocument/hover
+
+type HoverParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+}
+
+type MarkupContent struct {
+	/**
+	 * The type of the Markup, `plaintext` or `markdown`.
+	 */
+	Kind string `json:"kind"`
+
+	/**
+	 * The content itself.
+	 */
+	Value string `json:"value"`
+}
+
+type Hover struct {
+	/**
+	 * The hover's content, gopls always sends a MarkupContent.
+	 */
+	Contents MarkupContent `json:"contents"`
+
+	/**
+	 * An optional range is a range inside a text document
+	 * that is used to visualize a hover, e.g. by changing the background color.
+	 */
+	Range *Range `json:"range,omitempty"`
+}
+
+// HoverResponse has a nil Result if there is nothing to show at the position.
+type HoverResponse = GenericResponse[*Hover]
+
+// textDocument/documentSymbol
+
+type DocumentSymbolParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+type DocumentSymbolResult struct {
+	/**
+	 * The name of this symbol.
+	 */
+	Name string `json:"name"`
+
+	/**
+	 * The kind of this symbol.
+	 */
+	Kind SymbolKind `json:"kind"`
+
+	/**
+	 * Indicates if this symbol is deprecated.
+	 *
+	 * @deprecated Use tags instead
+	 */
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	/**
+	 * The location of this symbol. The location's range is used by a tool
+	 * to reveal the location in the editor. If the symbol is selected in the
+	 * tool the range's start information is used to position the cursor. So
+	 * the range usually spans more then the actual symbol's name and does
+	 * normally include things like visibility modifiers.
+	 *
+	 * The range doesn't have to denote a node range in the sense of an abstract
+	 * syntax tree. It can therefore not be used to re-construct a hierarchy of
+	 * the symbols.
+	 */
+	Location Location `json:"location"`
+
+	/**
+	 * The name of the symbol containing this symbol. This information is for
+	 * user interface purposes (e.g. to render a qualifier in the user interface
+	 * if necessary). It can't be used to re-infer a hierarchy for the document
+	 * symbols.
+	 */
+	ContainerName string `json:"containerName"`
+}
+
+type DocumentSymbolResponse = GenericResponse[[]DocumentSymbolResult]
+
+// textDocument/definition
+
+type DefinitionParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type DefinitionResponse = GenericResponse[Locations]
+
+// textDocument/typeDefinition
+
+type TypeDefinitionParams struct {
+	TextDocumentPositionParams
+	WorkDoneProgressParams
+	PartialResultParams
+}
+
+type TypeDefinitionResponse = GenericResponse[Locations]
+
+// workspace/symbol
+
+type WorkspaceSymbolParams struct {
+	WorkDoneProgressParams
+	PartialResultParams
+
+	/**
+	 * A query string to filter symbols by. Clients may send an empty
+	 * string here to request all symbols.
+	 */
+	Query string `json:"query"`
+}
+
+type SymbolInformation struct {
+	/**
+	 * The name of this symbol. gopls qualifies it as needed, e.g. `api.OntapAPIREST.VolumeCreate`.
+	 */
+	Name string `json:"name"`
+
+	/**
+	 * The kind of this symbol.
+	 */
+	Kind SymbolKind `json:"kind"`
+
+	/**
+	 * The location of this symbol.
+	 */
+	Location Location `json:"location"`
+
+	/**
+	 * The name of the symbol containing this symbol, gopls sends the package path.
+	 */
+	ContainerName string `json:"containerName,omitempty"`
+}
+
+type WorkspaceSymbolResponse = GenericResponse[[]SymbolInformation]
+
+// initialized
+
+type InitializedParams struct {
+}
+
+// $/cancelRequest
+
+type CancelParams struct {
+	/**
+	 * The request id to cancel.
+	 */
+	ID int `json:"id"`
+}
@@ -0,0 +1,28 @@
+package requests
+
+// GenericNotification is a notification of any method, whose params are P, left out if nil as for exit.
+type GenericNotification[P any] struct {
+	Jsonrpc string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  P      `json:"params,omitempty"`
+}
+
+func NewGenericNotification[P any](method string, params P) *GenericNotification[P] {
+	return &GenericNotification[P]{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+}
+
+// SendRequest hands the notification to the Requester, and waits for it to be written, so that the
+// notifications sent one after the other reach the server in that order.
+func (r *GenericNotification[P]) SendRequest(requestChan chan Request) {
+	written := make(chan struct{})
+	requestChan <- Request{
+		request:      *r,
+		notification: true,
+		written:      written,
+	}
+	<-written
+}
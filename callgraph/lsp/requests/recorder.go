@@ -0,0 +1,150 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// RecordHeader is the first line of a recording.
+type RecordHeader struct {
+	// WorkDir is the work directory of the recorded session, the replayer rewrites it to its own.
+	WorkDir string `json:"workDir"`
+	// PositionEncoding is the one negotiated at initialization, the recorded positions count in it.
+	PositionEncoding PositionEncodingKind `json:"positionEncoding,omitempty"`
+}
+
+// RecordEntry is one request and the response it got, the following lines of a recording.
+type RecordEntry struct {
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result,omitempty"`
+	Error  *ResponseError  `json:"error,omitempty"`
+}
+
+// Recorder writes every request passing through the Requester, along with its response, as JSON lines.
+// A nil Recorder records nothing.
+type Recorder struct {
+	writer   io.Writer
+	writerMU *sync.Mutex
+
+	workDir    string
+	headerOnce *sync.Once
+
+	// Requests sent, but not yet answered, by ID.
+	pending   map[int]RecordEntry
+	pendingMU *sync.Mutex
+}
+
+// NewRecorder records the session of workDir, the header being written by Begin once initialized.
+func NewRecorder(writer io.Writer, workDir string) *Recorder {
+	return &Recorder{
+		writer:     writer,
+		writerMU:   new(sync.Mutex),
+		workDir:    workDir,
+		headerOnce: new(sync.Once),
+		pending:    make(map[int]RecordEntry),
+		pendingMU:  new(sync.Mutex),
+	}
+}
+
+// Begin writes the header, along with the position encoding negotiated at initialization, before any request
+// is recorded. Only the first session writes it, reconnecting negotiates the same encoding again.
+func (r *Recorder) Begin(positionEncoding PositionEncodingKind) error {
+	if r == nil {
+		return nil
+	}
+
+	var err error
+	r.headerOnce.Do(func() {
+		err = r.writeLine(RecordHeader{WorkDir: r.workDir, PositionEncoding: positionEncoding})
+	})
+	return err
+}
+
+// recordRequest remembers the method and params of the request, till its response arrives.
+func (r *Recorder) recordRequest(id int, request interface{}) {
+	if r == nil {
+		return
+	}
+
+	requestJSON, err := json.Marshal(request)
+	if err != nil {
+		return
+	}
+
+	var entry RecordEntry
+	if err = json.Unmarshal(requestJSON, &entry); err != nil {
+		return
+	}
+
+	r.pendingMU.Lock()
+	r.pending[id] = entry
+	r.pendingMU.Unlock()
+}
+
+// recordResponse writes the request with the given ID along with its response.
+func (r *Recorder) recordResponse(id int, response json.RawMessage) {
+	if r == nil {
+		return
+	}
+
+	r.pendingMU.Lock()
+	entry, ok := r.pending[id]
+	delete(r.pending, id)
+	r.pendingMU.Unlock()
+	if !ok {
+		return
+	}
+
+	var message struct {
+		Result json.RawMessage `json:"result"`
+		Error  *ResponseError  `json:"error"`
+	}
+	if err := json.Unmarshal(response, &message); err != nil {
+		return
+	}
+	entry.Result, entry.Error = message.Result, message.Error
+
+	_ = r.writeLine(entry)
+}
+
+// forget drops the request with the given ID, it won't get a response worth recording.
+func (r *Recorder) forget(id int) {
+	if r == nil {
+		return
+	}
+
+	r.pendingMU.Lock()
+	delete(r.pending, id)
+	r.pendingMU.Unlock()
+}
+
+func (r *Recorder) writeLine(line interface{}) error {
+	lineJSON, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("#Recorder: failed to marshal -> %w", err)
+	}
+
+	r.writerMU.Lock()
+	defer r.writerMU.Unlock()
+	if _, err = r.writer.Write(append(lineJSON, '\n')); err != nil {
+		return fmt.Errorf("#Recorder: failed to write -> %w", err)
+	}
+
+	return nil
+}
+
+// recordKey identifies a request regardless of its ID, by its method and its params in canonical form.
+func recordKey(method string, params json.RawMessage) string {
+	var value interface{}
+	if err := json.Unmarshal(params, &value); err != nil {
+		return method + " " + strings.TrimSpace(string(params))
+	}
+
+	// Marshalling a generic value sorts the keys of objects.
+	canonical, _ := json.Marshal(value)
+	return method + " " + string(canonical)
+}
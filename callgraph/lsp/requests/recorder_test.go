@@ -0,0 +1,102 @@
+package requests_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+var _ = Describe("Recorder and Replayer", func() {
+	const (
+		recordedWorkDir = "/home/developer/trident"
+		replayWorkDir   = "/ci/trident"
+		relativeFile    = "/storage_drivers/ontap/api/abstraction.go"
+	)
+
+	var (
+		ctx       = context.Background()
+		recording *bytes.Buffer
+	)
+
+	// record sends an implementation request on a recorded session and returns its response.
+	record := func(server *lsptest.Server, line int) *ImplementationResponse {
+		conn := server.Conn()
+		recorder := NewRecorder(recording, recordedWorkDir)
+		Expect(recorder.Begin(PositionEncodingUTF8)).To(Succeed())
+
+		requestChan := make(chan Request, 10)
+		NewRequester(bufio.NewReader(conn), requestChan, conn, nil, recorder)
+
+		request := NewGenericRequest("textDocument/implementation", ImplementationParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{Uri: "file://" + recordedWorkDir + relativeFile},
+				Position:     Position{Line: line, Character: 1},
+			},
+		}, 42)
+		responseChan := make(chan json.RawMessage)
+		go request.SendRequest(requestChan, responseChan)
+		return ReadGenericResponse[[]Location](responseChan)
+	}
+
+	BeforeEach(func() {
+		recording = new(bytes.Buffer)
+
+		iface := lsptest.NewFunction(recordedWorkDir+relativeFile, 10, 1, "VolumeCreate", "")
+		impl := lsptest.NewFunction(recordedWorkDir+relativeFile, 100, 5, "VolumeCreate", "")
+		iface.Implementations = []Location{impl.Location()}
+
+		server := lsptest.NewServer()
+		server.Script(iface)
+		defer server.Close()
+
+		response := record(server, 10)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+	})
+
+	It("should write a header and one line per request", func() {
+		lines := strings.Split(strings.TrimSpace(recording.String()), "\n")
+		Expect(lines).To(HaveLen(2))
+		Expect(lines[0]).To(MatchJSON(`{"workDir": "` + recordedWorkDir + `", "positionEncoding": "utf-8"}`))
+		Expect(lines[1]).To(ContainSubstring(`"method":"textDocument/implementation"`))
+	})
+
+	It("should replay the recorded responses in another work directory without a server", func() {
+		replayer, err := NewReplayer(recording, replayWorkDir)
+		Expect(err).ToNot(HaveOccurred())
+		defer replayer.Close()
+
+		client := lsp.NewLsp(ctx, replayer, replayWorkDir, lsp.Config{})
+		Expect(client.Initialize(ctx, "trident")).To(Succeed())
+		// The recorded positions count in the encoding negotiated then, the replay negotiates it again.
+		Expect(client.PositionEncoding()).To(Equal(PositionEncodingUTF8))
+
+		response := <-client.Implementations(ctx, replayWorkDir+relativeFile, 10, 1)
+		Expect(response.Error).To(BeNil())
+		Expect(response.Result).To(HaveLen(1))
+		Expect(response.Result[0].Uri).To(Equal("file://" + replayWorkDir + relativeFile))
+		Expect(response.Result[0].Range.Start).To(Equal(Position{Line: 100, Character: 5}))
+	})
+
+	It("should answer requests which weren't recorded with an error", func() {
+		replayer, err := NewReplayer(recording, replayWorkDir)
+		Expect(err).ToNot(HaveOccurred())
+		defer replayer.Close()
+
+		client := lsp.NewLsp(ctx, replayer, replayWorkDir, lsp.Config{})
+		Expect(client.Initialize(ctx, "trident")).To(Succeed())
+
+		response := <-client.Implementations(ctx, replayWorkDir+relativeFile, 20, 1)
+		Expect(response.Error).ToNot(BeNil())
+		Expect(response.Error.Code).To(Equal(RequestFailed))
+	})
+})
@@ -0,0 +1,218 @@
+package requests
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/theshashankpal/api-collector/utils"
+)
+
+// Replayer is a transport which answers requests from a recording made by Recorder, instead of gopls.
+// Requests are matched by method and params, as IDs differ from one run to another.
+// Identical requests are answered in the recorded order, the last answer being repeated once exhausted.
+type Replayer struct {
+	// positionEncoding is the one negotiated by the recorded session, answered at initialization.
+	positionEncoding PositionEncodingKind
+
+	entries   map[string][]RecordEntry
+	served    map[string]int
+	entriesMU *sync.Mutex
+
+	// The client writes into inbound, and reads from outbound.
+	inboundReader  *io.PipeReader
+	inboundWriter  *io.PipeWriter
+	outboundReader *io.PipeReader
+	outboundWriter *io.PipeWriter
+	outbound       chan []byte
+	closeOnce      *sync.Once
+}
+
+// NewReplayer loads the recording, rewriting the recorded work directory to workDir,
+// so a recording made on one machine can be replayed on another.
+func NewReplayer(recording io.Reader, workDir string) (*Replayer, error) {
+	replayer := &Replayer{
+		entries:   make(map[string][]RecordEntry),
+		served:    make(map[string]int),
+		entriesMU: new(sync.Mutex),
+		outbound:  make(chan []byte, 128),
+		closeOnce: new(sync.Once),
+	}
+
+	scanner := bufio.NewScanner(recording)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+
+	var header RecordHeader
+	if !scanner.Scan() {
+		return nil, fmt.Errorf("#NewReplayer: recording is empty")
+	}
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("#NewReplayer: failed to unmarshal recording header -> %w", err)
+	}
+	replayer.positionEncoding = header.PositionEncoding
+
+	rewrite := func(raw []byte) []byte {
+		if header.WorkDir == "" || header.WorkDir == workDir {
+			return raw
+		}
+		return []byte(strings.ReplaceAll(string(raw), header.WorkDir, workDir))
+	}
+
+	for scanner.Scan() {
+		var entry RecordEntry
+		if err := json.Unmarshal(rewrite(scanner.Bytes()), &entry); err != nil {
+			return nil, fmt.Errorf("#NewReplayer: failed to unmarshal recording entry -> %w", err)
+		}
+		key := recordKey(entry.Method, entry.Params)
+		replayer.entries[key] = append(replayer.entries[key], entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("#NewReplayer: failed to read recording -> %w", err)
+	}
+
+	replayer.inboundReader, replayer.inboundWriter = io.Pipe()
+	replayer.outboundReader, replayer.outboundWriter = io.Pipe()
+	go replayer.serve()
+	go replayer.send()
+
+	return replayer, nil
+}
+
+func (r *Replayer) Read(b []byte) (int, error) {
+	return r.outboundReader.Read(b)
+}
+
+func (r *Replayer) Write(b []byte) (int, error) {
+	return r.inboundWriter.Write(b)
+}
+
+func (r *Replayer) Close() error {
+	r.closeOnce.Do(func() {
+		_ = r.inboundWriter.Close()
+		_ = r.outboundReader.Close()
+	})
+	return nil
+}
+
+// serve reads what the client sends and queues the answers.
+func (r *Replayer) serve() {
+	defer close(r.outbound)
+
+	reader := bufio.NewReader(r.inboundReader)
+	for {
+		contentLength, err := utils.FindTheContentLength(reader)
+		if err != nil {
+			return
+		}
+
+		content := make([]byte, contentLength)
+		if _, err = io.ReadFull(reader, content); err != nil {
+			return
+		}
+
+		var message struct {
+			ID     *int            `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err = json.Unmarshal(content, &message); err != nil {
+			continue
+		}
+
+		if message.ID == nil {
+			r.notified(message.Method)
+			continue
+		}
+
+		reply := map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      *message.ID,
+		}
+		switch message.Method {
+		case "initialize":
+			// The recorded params count in that encoding, they only match if the client converts to it again.
+			reply["result"] = InitializeResult{Capabilities: ServerCapabilities{PositionEncoding: r.positionEncoding}}
+		case "shutdown":
+			reply["result"] = nil
+		default:
+			entry, ok := r.lookup(message.Method, message.Params)
+			if !ok {
+				reply["error"] = &ResponseError{
+					Code:    RequestFailed,
+					Message: fmt.Sprintf("Replayer: no recorded response for %s %s", message.Method, message.Params),
+				}
+			} else if entry.Error != nil {
+				reply["error"] = entry.Error
+			} else {
+				reply["result"] = entry.Result
+			}
+		}
+		r.queue(reply)
+	}
+}
+
+// notified answers the notifications of the client, as gopls would.
+func (r *Replayer) notified(method string) {
+	switch method {
+	case "initialized":
+		// The session was recorded after gopls had loaded the packages, hence ready right away.
+		for _, value := range []WorkDoneProgressValue{
+			{Kind: WorkDoneProgressBegin, Title: "Setting up workspace"},
+			{Kind: WorkDoneProgressEnd, Message: "Finished loading packages."},
+		} {
+			r.queue(map[string]interface{}{
+				"jsonrpc": "2.0",
+				"method":  "$/progress",
+				"params":  ProgressParams[WorkDoneProgressValue]{Token: "replay", Value: value},
+			})
+		}
+	}
+}
+
+func (r *Replayer) lookup(method string, params json.RawMessage) (RecordEntry, bool) {
+	key := recordKey(method, params)
+
+	r.entriesMU.Lock()
+	defer r.entriesMU.Unlock()
+
+	entries, ok := r.entries[key]
+	if !ok {
+		return RecordEntry{}, false
+	}
+
+	index := r.served[key]
+	if index >= len(entries) {
+		index = len(entries) - 1
+	}
+	r.served[key] = index + 1
+
+	entry := entries[index]
+	if entry.Result == nil && entry.Error == nil {
+		entry.Result = json.RawMessage("null")
+	}
+	return entry, true
+}
+
+func (r *Replayer) queue(message map[string]interface{}) {
+	messageJSON, err := json.Marshal(message)
+	if err != nil {
+		return
+	}
+	r.outbound <- messageJSON
+}
+
+// send writes the queued answers for the client to read, in order.
+func (r *Replayer) send() {
+	for messageJSON := range r.outbound {
+		if _, err := io.WriteString(r.outboundWriter, utils.ConstructRequest(messageJSON)); err != nil {
+			break
+		}
+	}
+	// Drain, so that serve never blocks once the client is gone.
+	for range r.outbound {
+	}
+	_ = r.outboundWriter.Close()
+}
@@ -0,0 +1,56 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// GenericRequest is a request of any method, whose params are P. A method without params, such as shutdown,
+// has P be any and its params nil, which leaves them out.
+type GenericRequest[P any] struct {
+	Jsonrpc string `json:"jsonrpc"`
+	Method  string `json:"method"`
+	Params  P      `json:"params,omitempty"`
+	ID      int    `json:"id"`
+}
+
+// GenericResponse is the response of a GenericRequest, whose result is R.
+type GenericResponse[R any] struct {
+	Jsonrpc string         `json:"jsonrpc"`
+	ID      int            `json:"id"`
+	Result  R              `json:"result"`
+	Error   *ResponseError `json:"error"`
+}
+
+func NewGenericRequest[P any](method string, params P, id int) *GenericRequest[P] {
+	return &GenericRequest[P]{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+		ID:      id,
+	}
+}
+
+func (r *GenericRequest[P]) SendRequest(requestChan chan Request, responseChan chan json.RawMessage) {
+	requestChan <- Request{
+		request:      *r,
+		id:           r.ID,
+		responseChan: responseChan,
+	}
+}
+
+// ReadGenericResponse waits for the response, and decodes it straight into its type.
+func ReadGenericResponse[R any](responseChan chan json.RawMessage) *GenericResponse[R] {
+	response := <-responseChan
+
+	var genericResponse GenericResponse[R]
+	if err := json.Unmarshal(response, &genericResponse); err != nil {
+		return &GenericResponse[R]{
+			Error: &ResponseError{
+				Code:    JsonUnMarshalError,
+				Message: fmt.Sprintf("GenericResponse #ReadGenericResponse: failed to unmarshal -> %v", err),
+			},
+		}
+	}
+	return &genericResponse
+}
@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"github.com/theshashankpal/api-collector/utils"
 	"io"
-	"net"
 )
 
 // InitializeError provides additional information about initialization errors
@@ -43,8 +42,6 @@ type InitializeRequest struct {
 	Method  string           `json:"method"`
 	Params  InitializeParams `json:"params"`
 	ID      int              `json:"id"`
-
-	responseChan chan map[string]interface{}
 }
 
 type InitializeParams struct {
@@ -57,12 +54,23 @@ type InitializeParams struct {
 	WorkspaceFolders      []WorkspaceFolder  `json:"workspaceFolders,omitempty"`
 }
 
-func (r *InitializeRequest) NewRequest(workDir, name string, id int) *InitializeRequest {
+func (r *InitializeRequest) NewRequest(workDir, name string, initializationOptions interface{}, id int) *InitializeRequest {
 	return &InitializeRequest{
 		Jsonrpc: "2.0",
 		Method:  "initialize",
 		Params: InitializeParams{
-			ProcessID: nil,
+			ProcessID:             nil,
+			InitializationOptions: initializationOptions,
+			// gopls then reports the loading of the workspace through $/progress.
+			// Bytes are preferred, go/token counts them, UTF-16 is the encoding every server supports.
+			// gopls asks for its settings through workspace/configuration once initialized.
+			Capabilities: ClientCapabilities{
+				Window: &WindowClientCapabilities{WorkDoneProgress: true},
+				General: &GeneralClientCapabilities{
+					PositionEncodings: []PositionEncodingKind{PositionEncodingUTF8, PositionEncodingUTF16},
+				},
+				Workspace: &WorkspaceClientCapabilities{Configuration: true},
+			},
 			WorkspaceFolders: []WorkspaceFolder{
 				{
 					URI:  fmt.Sprintf("file://%s", workDir),
@@ -74,7 +82,7 @@ func (r *InitializeRequest) NewRequest(workDir, name string, id int) *Initialize
 	}
 }
 
-func (r *InitializeRequest) SendRequest(conn net.Conn) error {
+func (r *InitializeRequest) SendRequest(conn io.Writer) error {
 	requestJSON, err := json.Marshal(r)
 	if err != nil {
 		return err
@@ -83,7 +91,7 @@ func (r *InitializeRequest) SendRequest(conn net.Conn) error {
 	request := utils.ConstructRequest(requestJSON)
 
 	// Send the request
-	_, err = fmt.Fprintf(conn, request)
+	_, err = io.WriteString(conn, request)
 	if err != nil {
 		return err
 	}
@@ -115,9 +123,3 @@ func (r *InitializeRequest) ReadResponse(reader *bufio.Reader) (*InitializeRespo
 		}
 	}
 }
-
-type InitializeRequestInterface interface {
-	NewRequest(workDir, name string, id int) *InitializeRequest
-	SendRequest(conn net.Conn) error
-	ReadResponse(reader *bufio.Reader) (*InitializeResponse, error)
-}
@@ -2,134 +2,243 @@ package requests
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net"
 	"sync"
 
+	. "github.com/theshashankpal/api-collector/logger"
 	"github.com/theshashankpal/api-collector/utils"
 )
 
 type Request struct {
 	request      interface{}
 	id           int
-	responseChan chan map[string]interface{}
+	responseChan chan json.RawMessage
+	// notification, or reply to a request of the server, is only written, no response is awaited.
+	notification bool
+	// written, if set, is closed once the request is written, or failed to be.
+	written chan struct{}
 }
 
+// Requester writes the requests to the connection, and serves every response read back to the request
+// with the same ID, no matter the order they come in.
 type Requester struct {
-	reader           *bufio.Reader
-	neededRequests   map[int]struct{}
-	neededRequestsMU *sync.Mutex
-	cachedResponses  map[int]map[string]interface{}
+	reader *bufio.Reader
+
+	// pending holds the channel of every request sent and not answered yet.
+	pending   map[int]chan json.RawMessage
+	pendingMU *sync.Mutex
+	// closedErr is set once the connection is gone, failing all the requests from then on.
+	closedErr error
+
+	// requestChan also carries the replies to the requests of the server, which handlers answer.
+	requestChan chan Request
+	handlers    *Handlers
+
+	recorder *Recorder
 }
 
-func NewRequester(reader *bufio.Reader, requestChan chan Request, responseChan chan Request, conn net.Conn) *Requester {
+// NewRequester starts serving the requests sent on requestChan, handlers and recorder can be nil.
+func NewRequester(reader *bufio.Reader, requestChan chan Request, conn io.Writer, handlers *Handlers,
+	recorder *Recorder) *Requester {
 	requester := &Requester{
-		reader:           reader,
-		neededRequests:   make(map[int]struct{}),
-		cachedResponses:  make(map[int]map[string]interface{}),
-		neededRequestsMU: new(sync.Mutex),
+		reader:      reader,
+		pending:     make(map[int]chan json.RawMessage),
+		pendingMU:   new(sync.Mutex),
+		requestChan: requestChan,
+		handlers:    handlers,
+		recorder:    recorder,
 	}
 
 	// Start the go routines
-	go requester.submitRequest(requestChan, responseChan, conn)
-	go requester.readResponse(responseChan)
+	go requester.submitRequest(requestChan, conn)
+	go requester.readResponse()
 
 	return requester
 }
 
-func (r *Requester) submitRequest(request chan Request, responseReader chan Request, conn net.Conn) {
-	for {
-		select {
-		case req := <-request:
-			// Send the request
-			if req.request == nil {
-				// bad request handle it
-			} else {
-				// Send the request
-				requestJSON, err := json.Marshal(req.request)
-				requestWithHeader := utils.ConstructRequest(requestJSON)
-				_, err = fmt.Fprintf(conn, requestWithHeader)
-				if err != nil {
-					fmt.Printf("error sending request with request id %d : %v\n", req.id, err)
-				}
-
-				// Save it in the needed map
-				r.neededRequestsMU.Lock()
-				r.neededRequests[req.id] = struct{}{}
-				r.neededRequestsMU.Unlock()
-
-				// Now signal readReponse go routine that you can try to read the response of this request.
-				responseReader <- req
-			}
+func (r *Requester) submitRequest(requestChan chan Request, conn io.Writer) {
+	for req := range requestChan {
+		r.submit(req, conn)
+		if req.written != nil {
+			close(req.written)
 		}
 	}
 }
 
-func (r *Requester) readResponse(responseReader chan Request) {
+// submit writes the request to the connection, registering it as pending unless it is a notification.
+func (r *Requester) submit(req Request, conn io.Writer) {
+	if req.request == nil {
+		// bad request, nothing to send
+		return
+	}
+
+	requestJSON, err := json.Marshal(req.request)
+	if err != nil {
+		r.fail(req, JsonMarshalError, fmt.Sprintf("failed to marshal request %d -> %v", req.id, err))
+		return
+	}
+
+	if !req.notification {
+		// Save it in the pending map before sending, the response can be read before the write returns.
+		r.pendingMU.Lock()
+		if r.closedErr != nil {
+			r.pendingMU.Unlock()
+			r.fail(req, ConnectionClosed, fmt.Sprintf("request %d not sent -> %v", req.id, r.closedErr))
+			return
+		}
+		r.pending[req.id] = req.responseChan
+		r.pendingMU.Unlock()
+		r.recorder.recordRequest(req.id, req.request)
+	}
+
+	if _, err = io.WriteString(conn, utils.ConstructRequest(requestJSON)); err != nil {
+		Log(context.Background(), rf).Error().Err(err).Int("requestID", req.id).Msg("Error sending request")
+		if !req.notification && r.take(req.id) != nil {
+			r.fail(req, ConnectionClosed, fmt.Sprintf("failed to send request %d -> %v", req.id, err))
+		}
+	}
+}
+
+// readResponse reads every message from the connection, and hands each response to the request awaiting it.
+func (r *Requester) readResponse() {
 	for {
-		select {
-		case req := <-responseReader:
-			// Read the response
-			for {
-				// Check whether we have the response for this request cached or not?
-				if _, ok := r.cachedResponses[req.id]; ok {
-					req.responseChan <- r.cachedResponses[req.id]
-					// Now we've served the response, remove it from the cache and needed map
-					delete(r.cachedResponses, req.id)
-					r.neededRequestsMU.Lock()
-					delete(r.neededRequests, req.id)
-					r.neededRequestsMU.Unlock()
-					break
-				}
-
-				contentLength, err := utils.FindTheContentLength(r.reader)
-				if err != nil {
-					fmt.Printf("Error finding content length of request id %d : %v\n", req.id, err)
-				}
-
-				content := make([]byte, contentLength)
-				_, err = io.ReadFull(r.reader, content)
-				if err != nil {
-					fmt.Printf("Error reading response of request id %d : %v\n", req.id, err)
-				}
-
-				// Unmarshal JSON content
-				var message map[string]interface{}
-				err = json.Unmarshal(content, &message)
-				if err != nil {
-					fmt.Printf("Error unmarshalling response of request id %d : %v\n", req.id, err)
-				}
-
-				if _, ok := message["id"]; !ok {
-					// not of need, ignore it
-					continue
-				}
-
-				id := int(message["id"].(float64))
-
-				// Check whether we even require this response or not
-				r.neededRequestsMU.Lock()
-				if _, ok := r.neededRequests[id]; !ok {
-					// not of need, ignore it
-					r.neededRequestsMU.Unlock()
-					continue
-				}
-				r.neededRequestsMU.Unlock()
-
-				// See whether this response is of the request we are looking for
-				if id == req.id {
-					req.responseChan <- message
-					r.neededRequestsMU.Lock()
-					delete(r.neededRequests, req.id)
-					r.neededRequestsMU.Unlock()
-					break
-				} else {
-					// cache this response
-					r.cachedResponses[id] = message
-				}
-			}
+		contentLength, err := utils.FindTheContentLength(r.reader)
+		if err != nil {
+			r.close(err)
+			return
+		}
+
+		content := make([]byte, contentLength)
+		if _, err = io.ReadFull(r.reader, content); err != nil {
+			r.close(err)
+			return
 		}
+
+		// Only the envelope is decoded here, the response is decoded once, into its type, by whoever awaits it.
+		var message struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err = json.Unmarshal(content, &message); err != nil {
+			Log(context.Background(), rf).Error().Err(err).Msg("Error unmarshalling message")
+			continue
+		}
+
+		// Requests and notifications of the server carry a method, responses don't.
+		if message.Method != "" {
+			r.handle(content)
+			continue
+		}
+
+		// Our requests all have number IDs, anything else isn't ours.
+		var id int
+		if err = json.Unmarshal(message.ID, &id); err != nil {
+			continue
+		}
+
+		responseChan := r.take(id)
+		if responseChan == nil {
+			// Cancelled, or not ours, ignore it.
+			continue
+		}
+		r.recorder.recordResponse(id, content)
+
+		// Never hold the reader, each response is handed over on its own.
+		go func() {
+			responseChan <- content
+		}()
 	}
 }
+
+// handle hands the request or notification of the server to its handler. Notifications are handled in order,
+// as they come, while requests are answered on their own, so that a slow handler doesn't hold the reader.
+func (r *Requester) handle(content []byte) {
+	var message serverMessage
+	if err := json.Unmarshal(content, &message); err != nil {
+		Log(context.Background(), rf).Error().Err(err).Msg("Error unmarshalling server message")
+		return
+	}
+
+	if message.ID == nil {
+		r.handlers.notify(message)
+		return
+	}
+
+	go func() {
+		r.requestChan <- Request{
+			request:      r.handlers.reply(message),
+			notification: true,
+		}
+	}()
+}
+
+// take removes the request with the given ID from the pending ones, returning its channel or nil if not pending.
+func (r *Requester) take(id int) chan json.RawMessage {
+	r.pendingMU.Lock()
+	defer r.pendingMU.Unlock()
+
+	responseChan, ok := r.pending[id]
+	if !ok {
+		return nil
+	}
+	delete(r.pending, id)
+	return responseChan
+}
+
+// close fails every pending request, and those sent later, as no response will ever be read anymore.
+func (r *Requester) close(err error) {
+	Log(context.Background(), rf).Error().Err(err).Msg("Error reading from the connection")
+
+	r.pendingMU.Lock()
+	r.closedErr = err
+	pending := r.pending
+	r.pending = make(map[int]chan json.RawMessage)
+	r.pendingMU.Unlock()
+
+	for id, responseChan := range pending {
+		r.recorder.forget(id)
+		r.fail(Request{id: id, responseChan: responseChan}, ConnectionClosed,
+			fmt.Sprintf("connection closed before the response of request %d -> %v", id, err))
+	}
+}
+
+// fail answers the request with an error, as the server would.
+func (r *Requester) fail(req Request, code int, message string) {
+	if req.notification || req.responseChan == nil {
+		return
+	}
+
+	go func() {
+		req.responseChan <- ErrorResponse(req.id, code, message)
+	}()
+}
+
+// ErrorResponse is the response of the request with the given ID, failed with an error.
+func ErrorResponse(id int, code int, message string) json.RawMessage {
+	response, _ := json.Marshal(struct {
+		Jsonrpc string         `json:"jsonrpc"`
+		ID      int            `json:"id"`
+		Error   *ResponseError `json:"error"`
+	}{
+		Jsonrpc: "2.0",
+		ID:      id,
+		Error:   &ResponseError{Code: code, Message: message},
+	})
+	return response
+}
+
+// Closed tells whether the connection is gone, every request being failed with ConnectionClosed from then on.
+func (r *Requester) Closed() bool {
+	r.pendingMU.Lock()
+	defer r.pendingMU.Unlock()
+	return r.closedErr != nil
+}
+
+// Cancel stops awaiting the response of the request with the given ID, which is dropped whenever it arrives.
+func (r *Requester) Cancel(id int) {
+	r.take(id)
+	r.recorder.forget(id)
+}
@@ -0,0 +1,173 @@
+package requests_test
+
+import (
+	"bufio"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+var _ = Describe("Requester", func() {
+	const file = "/work/trident/storage_drivers/ontap/api/abstraction.go"
+
+	var (
+		server      *lsptest.Server
+		requestChan chan Request
+		handlers    *Handlers
+
+		volumeCreate = lsptest.NewFunction(file, 10, 1, "VolumeCreate", "")
+		volumeGet    = lsptest.NewFunction(file, 20, 1, "VolumeGet", "")
+		createImpl   = lsptest.NewFunction(file, 100, 5, "VolumeCreate", "")
+		getImpl      = lsptest.NewFunction(file, 200, 5, "VolumeGet", "")
+	)
+
+	implementations := func(line, id int) chan *ImplementationResponse {
+		request := NewGenericRequest("textDocument/implementation", ImplementationParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{Uri: "file://" + file},
+				Position:     Position{Line: line, Character: 1},
+			},
+		}, id)
+		implementationResponseChan := make(chan *ImplementationResponse)
+		responseChan := make(chan json.RawMessage)
+		go request.SendRequest(requestChan, responseChan)
+		go func() { implementationResponseChan <- ReadGenericResponse[[]Location](responseChan) }()
+		return implementationResponseChan
+	}
+
+	BeforeEach(func() {
+		volumeCreate.Implementations = []Location{createImpl.Location()}
+		volumeGet.Implementations = []Location{getImpl.Location()}
+
+		server = lsptest.NewServer()
+		server.Script(volumeCreate, volumeGet)
+
+		conn := server.Conn()
+		requestChan = make(chan Request, 10)
+		handlers = NewHandlers()
+		NewRequester(bufio.NewReader(conn), requestChan, conn, handlers, nil)
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should serve the response to the request it belongs to", func() {
+		response := <-implementations(10, 1)
+		Expect(response.ID).To(Equal(1))
+		Expect(response.Result).To(ConsistOf(createImpl.Location()))
+	})
+
+	It("should serve responses which arrive out of order", func() {
+		server.ReplyOutOfOrder(2)
+
+		createChan := implementations(10, 1)
+		getChan := implementations(20, 2)
+
+		createResponse := <-createChan
+		getResponse := <-getChan
+		Expect(createResponse.ID).To(Equal(1))
+		Expect(createResponse.Result).To(ConsistOf(createImpl.Location()))
+		Expect(getResponse.ID).To(Equal(2))
+		Expect(getResponse.Result).To(ConsistOf(getImpl.Location()))
+	})
+
+	It("should decode the response of a generic request into its type", func() {
+		request := NewGenericRequest("textDocument/implementation", ImplementationParams{
+			TextDocumentPositionParams: TextDocumentPositionParams{
+				TextDocument: TextDocumentIdentifier{Uri: "file://" + file},
+				Position:     Position{Line: 10, Character: 1},
+			},
+		}, 4)
+		responseChan := make(chan json.RawMessage)
+		go request.SendRequest(requestChan, responseChan)
+
+		response := ReadGenericResponse[[]Location](responseChan)
+		Expect(response.Error).To(BeNil())
+		Expect(response.ID).To(Equal(4))
+		Expect(response.Result).To(ConsistOf(createImpl.Location()))
+	})
+
+	It("should skip notifications while waiting for a response", func() {
+		server.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *ResponseError) {
+			Expect(server.Notify("window/logMessage", map[string]interface{}{"type": 3, "message": "hello"})).To(Succeed())
+			return []Location{getImpl.Location()}, nil
+		})
+
+		response := <-implementations(20, 3)
+		Expect(response.ID).To(Equal(3))
+		Expect(response.Result).To(ConsistOf(getImpl.Location()))
+	})
+	It("should not hold a response back behind a slower request", func() {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		server.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *ResponseError) {
+			var implementationParams ImplementationParams
+			Expect(json.Unmarshal(params, &implementationParams)).To(Succeed())
+			if implementationParams.Position.Line == 10 {
+				<-unblock
+			}
+			return []Location{getImpl.Location()}, nil
+		})
+
+		slowChan := implementations(10, 1)
+		response := <-implementations(20, 2)
+		Expect(response.ID).To(Equal(2))
+		Expect(response.Result).To(ConsistOf(getImpl.Location()))
+		Consistently(slowChan).ShouldNot(Receive())
+	})
+
+	It("should fail the pending requests once the connection drops", func() {
+		unblock := make(chan struct{})
+		defer close(unblock)
+		server.Handle("textDocument/implementation", func(params json.RawMessage) (interface{}, *ResponseError) {
+			<-unblock
+			return nil, nil
+		})
+
+		pendingChan := implementations(10, 1)
+		Eventually(func() []lsptest.Message {
+			return server.ReceivedMethod("textDocument/implementation")
+		}).Should(HaveLen(1))
+		Expect(server.Close()).To(Succeed())
+
+		var response *ImplementationResponse
+		Eventually(pendingChan).Should(Receive(&response))
+		Expect(response.Error).ToNot(BeNil())
+		Expect(response.Error.Code).To(Equal(ConnectionClosed))
+
+		// Requests sent from then on fail right away.
+		Eventually(implementations(20, 2)).Should(Receive(&response))
+		Expect(response.Error.Code).To(Equal(ConnectionClosed))
+	})
+	It("should reply to the requests of the server", func() {
+		handlers.HandleRequest("workspace/configuration", func(params json.RawMessage) (interface{}, *ResponseError) {
+			return []interface{}{nil}, nil
+		})
+
+		Expect(server.Call(7, "workspace/configuration", ConfigurationParams{Items: []ConfigurationItem{{Section: "gopls"}}})).To(Succeed())
+		Eventually(func() *lsptest.Message { return server.Reply(7) }).ShouldNot(BeNil())
+		Expect(server.Reply(7).Error).To(BeNil())
+		Expect(server.Reply(7).Result).To(MatchJSON(`[null]`))
+
+		Expect(server.Call(8, "workspace/unknown", nil)).To(Succeed())
+		Eventually(func() *lsptest.Message { return server.Reply(8) }).ShouldNot(BeNil())
+		Expect(server.Reply(8).Error.Code).To(Equal(MethodNotFound))
+	})
+
+	It("should hand the notifications of the server to their handler", func() {
+		messages := make(chan string, 1)
+		handlers.HandleNotification("window/logMessage", func(params json.RawMessage) {
+			var logMessageParams LogMessageParams
+			Expect(json.Unmarshal(params, &logMessageParams)).To(Succeed())
+			messages <- logMessageParams.Message
+		})
+
+		Expect(server.Notify("window/logMessage", LogMessageParams{Type: MessageTypeInfo, Message: "hello"})).To(Succeed())
+		Eventually(messages).Should(Receive(Equal("hello")))
+	})
+})
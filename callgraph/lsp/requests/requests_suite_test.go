@@ -0,0 +1,13 @@
+package requests_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRequests(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Requests Suite")
+}
@@ -0,0 +1,95 @@
+package requests
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// RequestHandler answers a request sent by the server, returning either a result or an error.
+type RequestHandler func(params json.RawMessage) (interface{}, *ResponseError)
+
+// NotificationHandler takes in a notification sent by the server.
+type NotificationHandler func(params json.RawMessage)
+
+// Handlers answer the requests and notifications the server sends on its own. gopls waits on the replies
+// to its requests, those without a handler are answered with MethodNotFound. Notifications without a
+// handler are dropped.
+type Handlers struct {
+	requests      map[string]RequestHandler
+	notifications map[string]NotificationHandler
+	handlersMU    *sync.RWMutex
+}
+
+func NewHandlers() *Handlers {
+	return &Handlers{
+		requests:      make(map[string]RequestHandler),
+		notifications: make(map[string]NotificationHandler),
+		handlersMU:    new(sync.RWMutex),
+	}
+}
+
+// HandleRequest registers the handler of the server requests of method, replacing the previous one.
+func (h *Handlers) HandleRequest(method string, handler RequestHandler) {
+	h.handlersMU.Lock()
+	defer h.handlersMU.Unlock()
+	h.requests[method] = handler
+}
+
+// HandleNotification registers the handler of the server notifications of method, replacing the previous one.
+func (h *Handlers) HandleNotification(method string, handler NotificationHandler) {
+	h.handlersMU.Lock()
+	defer h.handlersMU.Unlock()
+	h.notifications[method] = handler
+}
+
+// serverMessage is a request or a notification of the server, ID is nil for notifications.
+// IDs are kept as sent, JSON-RPC allows both numbers and strings.
+type serverMessage struct {
+	ID     interface{}     `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+}
+
+// reply answers the request of the server, a nil Handlers answers nothing but MethodNotFound.
+func (h *Handlers) reply(message serverMessage) map[string]interface{} {
+	reply := map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      message.ID,
+	}
+
+	var handler RequestHandler
+	if h != nil {
+		h.handlersMU.RLock()
+		handler = h.requests[message.Method]
+		h.handlersMU.RUnlock()
+	}
+
+	if handler == nil {
+		reply["error"] = &ResponseError{
+			Code:    MethodNotFound,
+			Message: fmt.Sprintf("method %q is not handled by the client", message.Method),
+		}
+	} else if result, responseError := handler(message.Params); responseError != nil {
+		reply["error"] = responseError
+	} else {
+		// The result is required on success, even if null.
+		reply["result"] = result
+	}
+	return reply
+}
+
+// notify hands the notification of the server to its handler, if any.
+func (h *Handlers) notify(message serverMessage) {
+	if h == nil {
+		return
+	}
+
+	h.handlersMU.RLock()
+	handler := h.notifications[message.Method]
+	h.handlersMU.RUnlock()
+
+	if handler != nil {
+		handler(message.Params)
+	}
+}
@@ -0,0 +1,121 @@
+package requests
+
+// MessageType is the severity of the messages shown or logged by window/showMessage and window/logMessage.
+type MessageType int
+
+const (
+	MessageTypeError   MessageType = 1
+	MessageTypeWarning MessageType = 2
+	MessageTypeInfo    MessageType = 3
+	MessageTypeLog     MessageType = 4
+)
+
+type LogMessageParams struct {
+	/**
+	 * The message type.
+	 */
+	Type MessageType `json:"type"`
+
+	/**
+	 * The actual message.
+	 */
+	Message string `json:"message"`
+}
+
+type ShowMessageParams = LogMessageParams
+
+type ConfigurationItem struct {
+	/**
+	 * The scope to get the configuration section for.
+	 */
+	ScopeUri string `json:"scopeUri,omitempty"`
+
+	/**
+	 * The configuration section asked for.
+	 */
+	Section string `json:"section,omitempty"`
+}
+
+type ConfigurationParams struct {
+	Items []ConfigurationItem `json:"items"`
+}
+
+type DiagnosticSeverity int
+
+const (
+	DiagnosticSeverityError       DiagnosticSeverity = 1
+	DiagnosticSeverityWarning     DiagnosticSeverity = 2
+	DiagnosticSeverityInformation DiagnosticSeverity = 3
+	DiagnosticSeverityHint        DiagnosticSeverity = 4
+)
+
+type Diagnostic struct {
+	/**
+	 * The range at which the message applies.
+	 */
+	Range Range `json:"range"`
+
+	/**
+	 * The diagnostic's severity. To avoid interpretation mismatches when a
+	 * server is used with different clients it is highly recommended that
+	 * servers always provide a severity value.
+	 */
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+
+	/**
+	 * A human-readable string describing the source of this
+	 * diagnostic, e.g. 'typescript' or 'super lint'.
+	 */
+	Source string `json:"source,omitempty"`
+
+	/**
+	 * The diagnostic's message.
+	 */
+	Message string `json:"message"`
+}
+
+type PublishDiagnosticsParams struct {
+	/**
+	 * The URI for which diagnostic information is reported.
+	 */
+	Uri string `json:"uri"`
+
+	/**
+	 * Optional the version number of the document the diagnostics are published for.
+	 */
+	Version int `json:"version,omitempty"`
+
+	/**
+	 * An array of diagnostic information items.
+	 */
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+// WorkDoneProgressValue is the value of the $/progress notifications reporting work done progress.
+type WorkDoneProgressValue struct {
+	/**
+	 * Either begin, report or end.
+	 */
+	Kind string `json:"kind"`
+
+	/**
+	 * Mandatory title of the progress operation, sent on begin only.
+	 */
+	Title string `json:"title,omitempty"`
+
+	/**
+	 * Optional, more detailed associated progress message.
+	 */
+	Message string `json:"message,omitempty"`
+
+	/**
+	 * Optional progress percentage to display (value 100 is considered 100%).
+	 */
+	Percentage *int `json:"percentage,omitempty"`
+}
+
+const (
+	WorkDoneProgressBegin  = "begin"
+	WorkDoneProgressReport = "report"
+	WorkDoneProgressEnd    = "end"
+)
@@ -1,7 +1,13 @@
 package requests
 
+import (
+	"encoding/json"
+	"strings"
+)
+
 // ServerCapabilities lists capabilities the server provides
 type ServerCapabilities struct {
+	PositionEncoding                 PositionEncodingKind             `json:"positionEncoding,omitempty"` // utf-16 if not set
 	TextDocumentSync                 interface{}                      `json:"textDocumentSync,omitempty"` // Can be either TextDocumentSyncOptions or a number
 	HoverProvider                    bool                             `json:"hoverProvider,omitempty"`
 	CompletionProvider               *CompletionOptions               `json:"completionProvider,omitempty"`
@@ -28,6 +34,42 @@ type ServerCapabilities struct {
 	Experimental                     interface{}                      `json:"experimental,omitempty"`
 }
 type ClientCapabilities struct {
+	Window    *WindowClientCapabilities    `json:"window,omitempty"`
+	General   *GeneralClientCapabilities   `json:"general,omitempty"`
+	Workspace *WorkspaceClientCapabilities `json:"workspace,omitempty"`
+}
+
+type WorkspaceClientCapabilities struct {
+	/**
+	 * The client supports `workspace/configuration` requests, the server then asks for its settings
+	 * rather than only reading its initialization options.
+	 */
+	Configuration bool `json:"configuration,omitempty"`
+}
+
+type GeneralClientCapabilities struct {
+	/**
+	 * The position encodings supported by the client, in decreasing order of preference.
+	 * The server picks one, and tells it in the `positionEncoding` of its capabilities.
+	 */
+	PositionEncodings []PositionEncodingKind `json:"positionEncodings,omitempty"`
+}
+
+// PositionEncodingKind is what the characters of the positions count, UTF-16 code units unless negotiated otherwise.
+type PositionEncodingKind string
+
+const (
+	PositionEncodingUTF8  PositionEncodingKind = "utf-8"
+	PositionEncodingUTF16 PositionEncodingKind = "utf-16"
+	PositionEncodingUTF32 PositionEncodingKind = "utf-32"
+)
+
+type WindowClientCapabilities struct {
+	/**
+	 * It indicates whether the client supports server initiated
+	 * progress using the `window/workDoneProgress/create` request.
+	 */
+	WorkDoneProgress bool `json:"workDoneProgress,omitempty"`
 }
 
 type WorkspaceFolder struct {
@@ -91,6 +133,36 @@ type TextDocumentIdentifier struct {
 	Uri string `json:"uri"`
 }
 
+type TextDocumentItem struct {
+	/**
+	 * The text document's URI.
+	 */
+	Uri string `json:"uri"`
+
+	/**
+	 * The text document's language identifier.
+	 */
+	LanguageId string `json:"languageId"`
+
+	/**
+	 * The version number of this document (it will increase after each
+	 * change, including undo/redo).
+	 */
+	Version int `json:"version"`
+
+	/**
+	 * The content of the opened text document.
+	 */
+	Text string `json:"text"`
+}
+
+type DidOpenTextDocumentParams struct {
+	/**
+	 * The document that was opened.
+	 */
+	TextDocument TextDocumentItem `json:"textDocument"`
+}
+
 type Position struct {
 	/**
 	 * Line position in a document (zero-based).
@@ -192,3 +264,30 @@ type Location struct {
 	Uri   string `json:"uri"`
 	Range Range  `json:"range"`
 }
+
+// Locations is the result of the requests answering `Location | Location[] | null`, such as textDocument/definition.
+// It is always decoded as a slice.
+type Locations []Location
+
+func (l *Locations) UnmarshalJSON(data []byte) error {
+	trimmed := strings.TrimSpace(string(data))
+	switch {
+	case trimmed == "null":
+		*l = Locations{}
+		return nil
+	case strings.HasPrefix(trimmed, "{"):
+		var location Location
+		if err := json.Unmarshal(data, &location); err != nil {
+			return err
+		}
+		*l = Locations{location}
+		return nil
+	default:
+		var locations []Location
+		if err := json.Unmarshal(data, &locations); err != nil {
+			return err
+		}
+		*l = locations
+		return nil
+	}
+}
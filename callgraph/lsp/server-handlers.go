@@ -0,0 +1,95 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	. "github.com/theshashankpal/api-collector/logger"
+)
+
+var shf = LogFields{Key: "layer", Value: "gopls"}
+
+// registerHandlers answers the requests gopls sends on its own, which it would otherwise wait on,
+// forwards its messages to the logger, tracks its progress and keeps the diagnostics it publishes.
+func (l *LSP) registerHandlers(ctx context.Context, handlers *Handlers, progress *progress) {
+	// Nothing to set up on the client side, acknowledging is enough.
+	for _, method := range []string{
+		"window/workDoneProgress/create",
+		"client/registerCapability",
+		"client/unregisterCapability",
+		"window/showMessageRequest",
+	} {
+		handlers.HandleRequest(method, acknowledge)
+	}
+
+	handlers.HandleRequest("workspace/configuration", func(params json.RawMessage) (interface{}, *ResponseError) {
+		var configurationParams ConfigurationParams
+		if err := json.Unmarshal(params, &configurationParams); err != nil {
+			return nil, &ResponseError{Code: InvalidParams, Message: err.Error()}
+		}
+		// One result per item, null leaving the server to its defaults.
+		results := make([]interface{}, len(configurationParams.Items))
+		for i, item := range configurationParams.Items {
+			if item.Section == "gopls" && !l.config.Settings.IsZero() {
+				results[i] = l.config.Settings
+			}
+		}
+		return results, nil
+	})
+
+	logMessage := func(params json.RawMessage) {
+		var logMessageParams LogMessageParams
+		if err := json.Unmarshal(params, &logMessageParams); err != nil {
+			return
+		}
+
+		event := Log(ctx, shf).Debug()
+		switch logMessageParams.Type {
+		case MessageTypeError:
+			event = Log(ctx, shf).Error()
+		case MessageTypeWarning:
+			event = Log(ctx, shf).Warn()
+		case MessageTypeInfo:
+			event = Log(ctx, shf).Info()
+		}
+		event.Msg(strings.TrimSpace(logMessageParams.Message))
+	}
+	handlers.HandleNotification("window/logMessage", logMessage)
+	handlers.HandleNotification("window/showMessage", logMessage)
+
+	handlers.HandleNotification("$/progress", progress.handle(ctx))
+
+	handlers.HandleNotification("textDocument/publishDiagnostics", func(params json.RawMessage) {
+		var publishDiagnosticsParams PublishDiagnosticsParams
+		if err := json.Unmarshal(params, &publishDiagnosticsParams); err != nil {
+			return
+		}
+
+		// Every publication replaces the previous diagnostics of the file.
+		filePath := filePathOf(publishDiagnosticsParams.Uri)
+		l.diagnosticsMU.Lock()
+		defer l.diagnosticsMU.Unlock()
+		if len(publishDiagnosticsParams.Diagnostics) == 0 {
+			delete(l.diagnostics, filePath)
+			return
+		}
+		l.diagnostics[filePath] = publishDiagnosticsParams.Diagnostics
+	})
+}
+
+func acknowledge(json.RawMessage) (interface{}, *ResponseError) {
+	return nil, nil
+}
+
+func (l *LSP) Diagnostics() map[string][]Diagnostic {
+	l.diagnosticsMU.Lock()
+	defer l.diagnosticsMU.Unlock()
+
+	diagnostics := make(map[string][]Diagnostic, len(l.diagnostics))
+	for filePath, fileDiagnostics := range l.diagnostics {
+		diagnostics[filePath] = fileDiagnostics
+	}
+	return diagnostics
+}
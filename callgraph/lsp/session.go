@@ -0,0 +1,245 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	. "github.com/theshashankpal/api-collector/logger"
+)
+
+// session is one connection to gopls, and the Requester serving it.
+type session struct {
+	conn        io.ReadWriteCloser
+	reader      *bufio.Reader
+	requestChan chan Request
+	requester   *Requester
+	progress    *progress
+	// positionEncoding is the one the server picked, at initialization.
+	positionEncoding PositionEncodingKind
+	// started is set once the Requester serves requestChan, nothing can be sent on the session before.
+	started atomic.Bool
+}
+
+// newSession reads the progress of gopls, taking the workspace as loaded once no progress has been active for quiet.
+func newSession(conn io.ReadWriteCloser, quiet time.Duration) *session {
+	return &session{
+		conn:     conn,
+		reader:   bufio.NewReader(conn),
+		progress: newProgress(quiet),
+	}
+}
+
+// current returns the session requests are sent on.
+func (l *LSP) current() *session {
+	l.sessionMU.RLock()
+	defer l.sessionMU.RUnlock()
+	return l.session
+}
+
+// start initializes the session, and waits for gopls to load the workspace.
+func (l *LSP) start(ctx context.Context, s *session) error {
+	// The initialize response is read straight from the connection, it is waited on so that ctx can cut it short.
+	initializeErrChan := make(chan error, 1)
+	go func() {
+		initializeErrChan <- l.initialize(ctx, s)
+	}()
+
+	select {
+	case err := <-initializeErrChan:
+		if err != nil {
+			return err
+		}
+	case <-ctx.Done():
+		return fmt.Errorf("#start: gave up waiting for initialize response -> %w", ctx.Err())
+	}
+
+	if err := l.config.Recorder.Begin(s.positionEncoding); err != nil {
+		return err
+	}
+
+	// Start the requester after initialization, gopls sends requests of its own while loading the workspace.
+	s.requestChan = make(chan Request, 10)
+	handlers := NewHandlers()
+	l.registerHandlers(ctx, handlers, s.progress)
+	s.requester = NewRequester(s.reader, s.requestChan, s.conn, handlers, l.config.Recorder)
+	s.started.Store(true)
+
+	// Sending initialized notification
+	NewGenericNotification("initialized", InitializedParams{}).SendRequest(s.requestChan)
+
+	// gopls only takes the workspace/configuration answers in once told the configuration changed.
+	if !l.config.Settings.IsZero() {
+		NewGenericNotification("workspace/didChangeConfiguration", DidChangeConfigurationParams{
+			Settings: map[string]GoplsSettings{"gopls": l.config.Settings},
+		}).SendRequest(s.requestChan)
+	}
+
+	l.openOverlay(ctx, s)
+	return l.waitReady(ctx, s)
+}
+
+// openOverlay opens every file of the overlay, with its overlaid content. gopls then analyzes that content
+// rather than the one on disk, for as long as the session lasts, a new session opening them again.
+func (l *LSP) openOverlay(ctx context.Context, s *session) {
+	if len(l.config.Overlay) == 0 {
+		return
+	}
+
+	Log(ctx, lf).Info().Int("files", len(l.config.Overlay)).Msg("Opening the overlaid files")
+	for _, filePath := range l.config.Overlay.Files() {
+		NewGenericNotification("textDocument/didOpen", DidOpenTextDocumentParams{
+			TextDocument: TextDocumentItem{
+				Uri:        fmt.Sprintf("file://%s", filePath),
+				LanguageId: languageID(filePath),
+				Version:    1,
+				Text:       string(l.config.Overlay[filePath]),
+			},
+		}).SendRequest(s.requestChan)
+	}
+}
+
+// languageID is the language identifier gopls expects for the file.
+func languageID(filePath string) string {
+	switch filepath.Base(filePath) {
+	case "go.mod", "go.sum", "go.work":
+		return filepath.Base(filePath)
+	}
+	if filepath.Ext(filePath) == ".go" {
+		return "go"
+	}
+	return "plaintext"
+}
+
+// initialize sends the initialize request, and reads its response.
+func (l *LSP) initialize(ctx context.Context, s *session) error {
+	Log(ctx, lf).Debug().Str("workDir", l.workdir).Msg("Sending initialize request")
+	initializeRequest := (&InitializeRequest{}).NewRequest(l.workdir, l.name, l.config.Settings, int(uuid.New().ID()))
+	err := initializeRequest.SendRequest(s.conn)
+	if err != nil {
+		return err
+	}
+
+	initializeResponse, err := initializeRequest.ReadResponse(s.reader)
+	if err != nil {
+		return err
+	}
+
+	if initializeResponse.Error != nil {
+		return fmt.Errorf("#initialize: failed to get initialize response -> %w", initializeResponse.Error)
+	}
+
+	s.positionEncoding = initializeResponse.Result.Capabilities.PositionEncoding
+	if s.positionEncoding == "" {
+		s.positionEncoding = PositionEncodingUTF16
+	}
+	Log(ctx, lf).Debug().Str("positionEncoding", string(s.positionEncoding)).Msg("Initialized")
+	return nil
+}
+
+// PositionEncoding is what the characters of the positions exchanged with the server count.
+func (l *LSP) PositionEncoding() PositionEncodingKind {
+	return l.current().positionEncoding
+}
+
+// waitReady waits for gopls to report, through $/progress, that the workspace is loaded, or for it to report nothing.
+func (l *LSP) waitReady(ctx context.Context, s *session) error {
+	var timeout <-chan time.Time
+	if l.config.ReadyTimeout > 0 {
+		timer := time.NewTimer(l.config.ReadyTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	idle := s.progress.awaitBegin(ctx, l.config.readyIdleTimeout())
+	defer idle.Stop()
+
+	Log(ctx, lf).Info().Msg("Waiting for gopls to load the workspace")
+	select {
+	case <-s.progress.ready:
+		Log(ctx, lf).Info().Msg("Workspace loaded")
+		return nil
+	case <-timeout:
+		return fmt.Errorf("#waitReady: workspace not loaded within %s", l.config.ReadyTimeout)
+	case <-ctx.Done():
+		return fmt.Errorf("#waitReady: gave up waiting for the workspace to load -> %w", ctx.Err())
+	}
+}
+
+// reconnect replaces the failed session, whose connection dropped, by a new one dialed with Config.Dial.
+// Requests failing together all call it, only the first one reconnects, the others find the session replaced.
+func (l *LSP) reconnect(ctx context.Context, failed *session) error {
+	Log(ctx, lf).Trace().Msg(">>>> reconnect")
+	defer Log(ctx, lf).Trace().Msg("<<<< reconnect")
+
+	l.reconnectMU.Lock()
+	defer l.reconnectMU.Unlock()
+
+	if l.current() != failed {
+		return nil
+	}
+	if l.config.Dial == nil || l.shuttingDown.Load() {
+		return fmt.Errorf("#reconnect: not reconnecting")
+	}
+	if l.reconnects >= l.config.MaxReconnects {
+		return fmt.Errorf("#reconnect: gave up after %d reconnections", l.reconnects)
+	}
+	l.reconnects++
+
+	// The request which noticed is only the first of many, its deadline shouldn't cut the reconnection short.
+	ctx = context.WithoutCancel(ctx)
+	Log(ctx, lf).Warn().Int("reconnection", l.reconnects).Msg("Connection to gopls dropped, reconnecting")
+	_ = failed.conn.Close()
+
+	conn, err := l.config.Dial(ctx)
+	if err != nil {
+		return fmt.Errorf("#reconnect: failed to dial gopls -> %w", err)
+	}
+
+	s := newSession(conn, l.config.readyQuietPeriod())
+	if err = l.start(ctx, s); err != nil {
+		_ = conn.Close()
+		return fmt.Errorf("#reconnect: failed to initialize the new session -> %w", err)
+	}
+
+	l.sessionMU.Lock()
+	l.session = s
+	l.sessionMU.Unlock()
+
+	Log(ctx, lf).Info().Int("reconnection", l.reconnects).Msg("Reconnected to gopls")
+	return nil
+}
+
+// Shutdown asks gopls to shut down and exit, then closes the connection. The requests still in flight fail,
+// without reconnecting.
+func (l *LSP) Shutdown(ctx context.Context) error {
+	Log(ctx, lf).Trace().Msg(">>>> Shutdown")
+	defer Log(ctx, lf).Trace().Msg("<<<< Shutdown")
+
+	l.shuttingDown.Store(true)
+	s := l.current()
+	if !l.initialized {
+		return s.conn.Close()
+	}
+
+	// Neither shutdown nor exit have params, gopls refuses them even null.
+	var shutdownErr error
+	if shutdownResponse := <-send[any](ctx, l, "shutdown", any(nil)); shutdownResponse.Error != nil {
+		shutdownErr = fmt.Errorf("#Shutdown: failed to shut down gopls -> %w", shutdownResponse.Error)
+	} else {
+		// Exiting without the shutdown acknowledged makes gopls exit with an error.
+		NewGenericNotification("exit", any(nil)).SendRequest(s.requestChan)
+	}
+
+	if err := s.conn.Close(); err != nil && shutdownErr == nil {
+		shutdownErr = fmt.Errorf("#Shutdown: failed to close the connection -> %w", err)
+	}
+	return shutdownErr
+}
@@ -0,0 +1,159 @@
+package lsp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/theshashankpal/api-collector/callgraph"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	. "github.com/theshashankpal/api-collector/logger"
+)
+
+var slf = LogFields{Key: "layer", Value: "sharded-lsp"}
+
+// ShardedLSP spreads the requests across several gopls sessions, all on the same workspace. The requests
+// about a file always go to the same session, hashing the file path, so that its caches stay warm.
+type ShardedLSP struct {
+	shards []*AbstractionLSP
+
+	// routed counts the requests sent to every shard.
+	routed   []int
+	routedMU *sync.Mutex
+}
+
+// NewShardedLSP opens a session per connection, the config applies to each one of them.
+func NewShardedLSP(ctx context.Context, conns []io.ReadWriteCloser, workDir, name string, config Config) *ShardedLSP {
+	Log(ctx, slf).Trace().Msg(">>>> NewShardedLSP")
+	defer Log(ctx, slf).Trace().Msg("<<<< NewShardedLSP")
+
+	Log(ctx, slf).Debug().Int("shards", len(conns)).Msg("Instantiating new sharded LSP")
+	shards := make([]*AbstractionLSP, 0, len(conns))
+	for _, conn := range conns {
+		shards = append(shards, NewAbstractionLSP(ctx, conn, workDir, name, config))
+	}
+	return &ShardedLSP{
+		shards:   shards,
+		routed:   make([]int, len(shards)),
+		routedMU: new(sync.Mutex),
+	}
+}
+
+// Initialize initializes every shard at once, gopls loading the workspace takes a while.
+func (s *ShardedLSP) Initialize(ctx context.Context) error {
+	Log(ctx, slf).Trace().Msg(">>>> Initialize")
+	defer Log(ctx, slf).Trace().Msg("<<<< Initialize")
+
+	return s.all(func(shard *AbstractionLSP) error {
+		return shard.Initialize(ctx)
+	})
+}
+
+func (s *ShardedLSP) OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyOutgoingCallResponse {
+	return s.shard(filePath).OutgoingCalls(ctx, filePath, line, character)
+}
+
+func (s *ShardedLSP) IncomingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyIncomingCallResponse {
+	return s.shard(filePath).IncomingCalls(ctx, filePath, line, character)
+}
+
+func (s *ShardedLSP) Implementations(ctx context.Context, filePath string, line, character int) chan *ImplementationResponse {
+	return s.shard(filePath).Implementations(ctx, filePath, line, character)
+}
+
+func (s *ShardedLSP) References(ctx context.Context, filePath string, line int, character int) chan *ReferenceResponse {
+	return s.shard(filePath).References(ctx, filePath, line, character)
+}
+
+func (s *ShardedLSP) Hover(ctx context.Context, filePath string, line, character int) chan *HoverResponse {
+	return s.shard(filePath).Hover(ctx, filePath, line, character)
+}
+
+func (s *ShardedLSP) DocumentSymbol(ctx context.Context, filePath string) chan *DocumentSymbolResponse {
+	return s.shard(filePath).DocumentSymbol(ctx, filePath)
+}
+
+func (s *ShardedLSP) Definition(ctx context.Context, filePath string, line, character int) chan *DefinitionResponse {
+	return s.shard(filePath).Definition(ctx, filePath, line, character)
+}
+
+func (s *ShardedLSP) TypeDefinition(ctx context.Context, filePath string, line, character int) chan *TypeDefinitionResponse {
+	return s.shard(filePath).TypeDefinition(ctx, filePath, line, character)
+}
+
+// WorkspaceSymbol isn't about a file, any shard answers for the whole workspace, hashing the query only spreads
+// the load across them.
+func (s *ShardedLSP) WorkspaceSymbol(ctx context.Context, query string) chan *WorkspaceSymbolResponse {
+	return s.shard(query).WorkspaceSymbol(ctx, query)
+}
+
+func (s *ShardedLSP) Shutdown(ctx context.Context) error {
+	Log(ctx, slf).Trace().Msg(">>>> Shutdown")
+	defer Log(ctx, slf).Trace().Msg("<<<< Shutdown")
+
+	return s.all(func(shard *AbstractionLSP) error {
+		return shard.Shutdown(ctx)
+	})
+}
+
+// Summary adds up the statistics of the shards, along with the requests routed to each of them.
+// The compile errors are the same for every shard, each one loading the whole workspace.
+func (s *ShardedLSP) Summary() callgraph.Summary {
+	summary := callgraph.Summary{Counts: make(map[string]int)}
+	compileErrors := make(map[string]struct{})
+	for _, shard := range s.shards {
+		shardSummary := shard.Summary()
+		for key, count := range shardSummary.Counts {
+			summary.Counts[key] += count
+		}
+		for _, compileError := range shardSummary.CompileErrors {
+			compileErrors[compileError] = struct{}{}
+		}
+	}
+
+	for compileError := range compileErrors {
+		summary.CompileErrors = append(summary.CompileErrors, compileError)
+	}
+	sort.Strings(summary.CompileErrors)
+	summary.Counts["compileErrors"] = len(summary.CompileErrors)
+
+	s.routedMU.Lock()
+	for i, count := range s.routed {
+		summary.Counts[fmt.Sprintf("shard.%d", i)] = count
+	}
+	s.routedMU.Unlock()
+	return summary
+}
+
+// shard picks the shard of the file.
+func (s *ShardedLSP) shard(filePath string) *AbstractionLSP {
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(filePath))
+	i := int(hash.Sum32() % uint32(len(s.shards)))
+
+	s.routedMU.Lock()
+	s.routed[i]++
+	s.routedMU.Unlock()
+	return s.shards[i]
+}
+
+// all runs f on every shard at once, and joins their errors.
+func (s *ShardedLSP) all(f func(shard *AbstractionLSP) error) error {
+	errs := make([]error, len(s.shards))
+	wg := new(sync.WaitGroup)
+	for i, shard := range s.shards {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := f(shard); err != nil {
+				errs[i] = fmt.Errorf("shard %d -> %w", i, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
@@ -0,0 +1,108 @@
+package lsp_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+var _ = Describe("ShardedLSP", func() {
+	const (
+		workDir = "/work/trident"
+		detail  = "github.com/netapp/trident/storage_drivers/ontap/api"
+		shards  = 3
+	)
+
+	var (
+		ctx       = context.Background()
+		servers   []*lsptest.Server
+		functions []lsptest.Function
+		client    *lsp.ShardedLSP
+	)
+
+	BeforeEach(func() {
+		functions = nil
+		for i := 0; i < 10; i++ {
+			file := fmt.Sprintf("%s/storage_drivers/ontap/api/file_%d.go", workDir, i)
+			functions = append(functions, lsptest.NewFunction(file, 10, 5, fmt.Sprintf("Function%d", i), detail))
+		}
+
+		servers = nil
+		conns := make([]io.ReadWriteCloser, 0, shards)
+		for i := 0; i < shards; i++ {
+			server := lsptest.NewServer()
+			server.Script(functions...)
+			servers = append(servers, server)
+			conns = append(conns, server.Conn())
+		}
+
+		client = lsp.NewShardedLSP(ctx, conns, workDir, "trident", lsp.Config{})
+		Expect(client.Initialize(ctx)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		for _, server := range servers {
+			Expect(server.Close()).To(Succeed())
+		}
+	})
+
+	// receivedBy returns the index of the servers which got the implementation requests of the file.
+	receivedBy := func(file string) []int {
+		var indexes []int
+		for i, server := range servers {
+			for _, message := range server.ReceivedMethod("textDocument/implementation") {
+				var params TextDocumentPositionParams
+				Expect(json.Unmarshal(message.Params, &params)).To(Succeed())
+				if params.TextDocument.Uri == "file://"+file {
+					indexes = append(indexes, i)
+				}
+			}
+		}
+		return indexes
+	}
+
+	It("should initialize every session", func() {
+		for _, server := range servers {
+			Expect(server.ReceivedMethod("initialize")).To(HaveLen(1))
+			Expect(server.ReceivedMethod("initialized")).To(HaveLen(1))
+		}
+	})
+
+	It("should always send the requests about a file to the same session", func() {
+		for _, function := range functions {
+			file := function.Location().Uri[len("file://"):]
+			for i := 0; i < 3; i++ {
+				Expect((<-client.Implementations(ctx, file, 10, 5)).Error).To(BeNil())
+			}
+
+			indexes := receivedBy(file)
+			Expect(indexes).To(HaveLen(3))
+			Expect(indexes).To(HaveEach(indexes[0]))
+		}
+
+		// Ten files are spread across more than a single session.
+		var used int
+		for _, server := range servers {
+			if len(server.ReceivedMethod("textDocument/implementation")) > 0 {
+				used++
+			}
+		}
+		Expect(used).To(BeNumerically(">", 1))
+		Expect(client.Summary().Counts).To(HaveKeyWithValue("shard.0", len(servers[0].ReceivedMethod("textDocument/implementation"))))
+	})
+
+	It("should shut every session down", func() {
+		Expect(client.Shutdown(ctx)).To(Succeed())
+		for _, server := range servers {
+			Expect(server.ReceivedMethod("shutdown")).To(HaveLen(1))
+		}
+	})
+})
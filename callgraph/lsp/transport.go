@@ -0,0 +1,136 @@
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	. "github.com/theshashankpal/api-collector/logger"
+)
+
+var trf = LogFields{Key: "layer", Value: "lsp-transport"}
+
+// goplsExitTimeout is how long Close waits for gopls to exit on its own before killing it.
+const goplsExitTimeout = 5 * time.Second
+
+// Dial connects to an already running gopls server.
+// An address of the form unix:<path> or unix://<path> is dialed as a unix socket, anything else over TCP.
+func Dial(ctx context.Context, address string) (io.ReadWriteCloser, error) {
+	Log(ctx, trf).Trace().Msg(">>>> Dial")
+	defer Log(ctx, trf).Trace().Msg("<<<< Dial")
+
+	network := "tcp"
+	if strings.HasPrefix(address, "unix:") {
+		network = "unix"
+		address = strings.TrimPrefix(strings.TrimPrefix(address, "unix:"), "//")
+	}
+
+	Log(ctx, trf).Debug().Str("network", network).Str("address", address).Msg("Dialing gopls server")
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, fmt.Errorf("#Dial: failed to connect to gopls server at %s -> %w", address, err)
+	}
+
+	return conn, nil
+}
+
+// GoplsProcess is a gopls child process which speaks LSP over its stdin/stdout.
+type GoplsProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *os.File
+	exited chan struct{} // closed once gopls has exited
+}
+
+// StartGopls spawns bin (gopls by default) in workDir and returns its stdio as the transport.
+func StartGopls(ctx context.Context, bin, workDir string, args ...string) (*GoplsProcess, error) {
+	Log(ctx, trf).Trace().Msg(">>>> StartGopls")
+	defer Log(ctx, trf).Trace().Msg("<<<< StartGopls")
+
+	if bin == "" {
+		bin = "gopls"
+	}
+
+	cmd := exec.Command(bin, args...)
+	cmd.Dir = workDir
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("#StartGopls: failed to get stdin of %s -> %w", bin, err)
+	}
+	// Not using cmd.StdoutPipe, as cmd.Wait closes it and we could lose the last messages.
+	stdout, stdoutWriter, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("#StartGopls: failed to create stdout pipe of %s -> %w", bin, err)
+	}
+	cmd.Stdout = stdoutWriter
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("#StartGopls: failed to get stderr of %s -> %w", bin, err)
+	}
+
+	Log(ctx, trf).Debug().Str("bin", bin).Strs("args", args).Msg("Starting gopls process")
+	err = cmd.Start()
+	// The child has its own copy of the write end now.
+	_ = stdoutWriter.Close()
+	if err != nil {
+		_ = stdout.Close()
+		return nil, fmt.Errorf("#StartGopls: failed to start %s -> %w", bin, err)
+	}
+
+	// gopls writes its own logs to stderr, forward them to our logger.
+	go func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			Log(ctx, trf).Debug().Int("pid", cmd.Process.Pid).Msg(scanner.Text())
+		}
+	}()
+
+	process := &GoplsProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		stdout: stdout,
+		exited: make(chan struct{}),
+	}
+
+	go func() {
+		err := cmd.Wait()
+		Log(ctx, trf).Debug().Int("pid", cmd.Process.Pid).Err(err).Msg("gopls process exited")
+		close(process.exited)
+	}()
+
+	Log(ctx, trf).Info().Int("pid", cmd.Process.Pid).Msg("gopls process started")
+	return process, nil
+}
+
+func (p *GoplsProcess) Read(b []byte) (int, error) {
+	return p.stdout.Read(b)
+}
+
+func (p *GoplsProcess) Write(b []byte) (int, error) {
+	return p.stdin.Write(b)
+}
+
+// Close closes stdin of gopls, which makes it exit, and waits for it.
+// If gopls doesn't exit in time, it is killed.
+func (p *GoplsProcess) Close() error {
+	_ = p.stdin.Close()
+
+	select {
+	case <-p.exited:
+	case <-time.After(goplsExitTimeout):
+		if err := p.cmd.Process.Kill(); err != nil {
+			return fmt.Errorf("#Close: failed to kill gopls process -> %w", err)
+		}
+		<-p.exited
+	}
+
+	return p.stdout.Close()
+}
@@ -0,0 +1,137 @@
+package lsp_test
+
+import (
+	"context"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+)
+
+var _ = Describe("Transport", func() {
+	const workDir = "/work/trident"
+
+	var ctx = context.Background()
+
+	Describe("Dial", func() {
+		var (
+			server  *lsptest.Server
+			tempDir string
+		)
+
+		// serve hands the first connection the listener accepts over to the fake server.
+		serve := func(listener net.Listener) {
+			go func() {
+				defer listener.Close()
+				conn, err := listener.Accept()
+				if err != nil {
+					return
+				}
+				go func() {
+					_, _ = io.Copy(conn, server.Conn())
+					_ = conn.Close()
+				}()
+				_, _ = io.Copy(server.Conn(), conn)
+			}()
+		}
+
+		// initialize dials the address and initializes a session over the connection.
+		initialize := func(address string) {
+			conn, err := lsp.Dial(ctx, address)
+			Expect(err).ToNot(HaveOccurred())
+			defer conn.Close()
+
+			client := lsp.NewLsp(ctx, conn, workDir, lsp.Config{})
+			Expect(client.Initialize(ctx, "trident")).To(Succeed())
+			Expect(server.ReceivedMethod("initialize")).To(HaveLen(1))
+		}
+
+		BeforeEach(func() {
+			server = lsptest.NewServer()
+
+			var err error
+			tempDir, err = os.MkdirTemp("", "transport")
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			Expect(server.Close()).To(Succeed())
+			Expect(os.RemoveAll(tempDir)).To(Succeed())
+		})
+
+		It("should dial a unix socket, given as unix:<path>", func() {
+			socket := filepath.Join(tempDir, "gopls.sock")
+			listener, err := net.Listen("unix", socket)
+			Expect(err).ToNot(HaveOccurred())
+			serve(listener)
+
+			initialize("unix:" + socket)
+		})
+
+		It("should dial a unix socket, given as unix://<path>", func() {
+			socket := filepath.Join(tempDir, "gopls.sock")
+			listener, err := net.Listen("unix", socket)
+			Expect(err).ToNot(HaveOccurred())
+			serve(listener)
+
+			initialize("unix://" + socket)
+		})
+
+		It("should dial any other address over TCP", func() {
+			listener, err := net.Listen("tcp", "127.0.0.1:0")
+			Expect(err).ToNot(HaveOccurred())
+			serve(listener)
+
+			initialize(listener.Addr().String())
+		})
+
+		It("should fail if nothing listens at the address", func() {
+			_, err := lsp.Dial(ctx, "unix:"+filepath.Join(tempDir, "missing.sock"))
+			Expect(err).To(MatchError(ContainSubstring("failed to connect to gopls server")))
+		})
+	})
+
+	Describe("GoplsProcess", func() {
+		It("should speak over the stdio of the process, and wait for it to exit once its stdin is closed", func() {
+			// cat echoes what it reads, and exits once its stdin is closed.
+			process, err := lsp.StartGopls(ctx, "cat", os.TempDir())
+			Expect(err).ToNot(HaveOccurred())
+
+			_, err = process.Write([]byte("Content-Length: 2\r\n\r\n{}"))
+			Expect(err).ToNot(HaveOccurred())
+			echoed := make([]byte, len("Content-Length: 2\r\n\r\n{}"))
+			_, err = io.ReadFull(process, echoed)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(echoed)).To(Equal("Content-Length: 2\r\n\r\n{}"))
+
+			start := time.Now()
+			Expect(process.Close()).To(Succeed())
+			Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+		})
+
+		It("should kill the process if it doesn't exit in time once its stdin is closed", func() {
+			// sleep never reads its stdin, closing it doesn't make it exit.
+			process, err := lsp.StartGopls(ctx, "sleep", os.TempDir(), "60")
+			Expect(err).ToNot(HaveOccurred())
+
+			start := time.Now()
+			Expect(process.Close()).To(Succeed())
+			Expect(time.Since(start)).To(And(
+				BeNumerically(">=", 5*time.Second),
+				BeNumerically("<", 60*time.Second),
+			))
+		})
+
+		It("should fail to start a binary which doesn't exist", func() {
+			_, err := lsp.StartGopls(ctx, filepath.Join(os.TempDir(), "no-such-gopls"), os.TempDir())
+			Expect(err).To(MatchError(ContainSubstring("failed to start")))
+		})
+	})
+})
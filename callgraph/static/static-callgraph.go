@@ -0,0 +1,564 @@
+package static
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"golang.org/x/tools/go/callgraph"
+	"golang.org/x/tools/go/callgraph/cha"
+	"golang.org/x/tools/go/callgraph/vta"
+	"golang.org/x/tools/go/packages"
+	"golang.org/x/tools/go/ssa"
+	"golang.org/x/tools/go/ssa/ssautil"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	. "github.com/theshashankpal/api-collector/logger"
+	"github.com/theshashankpal/api-collector/overlay"
+)
+
+var scf = LogFields{Key: "layer", Value: "static-callgraph"}
+
+// Algorithm is the call-graph construction algorithm used by StaticCallGraph.
+type Algorithm string
+
+const (
+	// CHA is Class Hierarchy Analysis, sound but over-approximates dynamic calls.
+	CHA Algorithm = "cha"
+	// VTA is Variable Type Analysis, it refines the CHA graph using type flow.
+	VTA Algorithm = "vta"
+)
+
+// StaticCallGraph implements callgraph.CallGraph on top of golang.org/x/tools/go/callgraph,
+// so that no gopls server is needed. Positions are zero based, same as the LSP backend.
+type StaticCallGraph struct {
+	workDir   string
+	algorithm Algorithm
+	// overlay is loaded on top of the files of workDir.
+	overlay overlay.Overlay
+
+	fset  *token.FileSet
+	prog  *ssa.Program
+	graph *callgraph.Graph
+	pkgs  []*packages.Package
+
+	// funcsByLine indexes every declared function and method (interface methods included)
+	// by "file:line", so that a position coming from the traverser can be resolved.
+	funcsByLine map[string][]*types.Func
+
+	// identsByLine indexes the identifiers of the workspace packages by "file:line",
+	// along with the object they declare or use, for Definition and TypeDefinition.
+	identsByLine map[string][]identObject
+	workspace    []*packages.Package
+}
+
+type identObject struct {
+	ident *ast.Ident
+	obj   types.Object
+}
+
+func NewStaticCallGraph(ctx context.Context, workDir string, overlay overlay.Overlay, algorithm Algorithm) *StaticCallGraph {
+	Log(ctx, scf).Trace().Msg(">>>> NewStaticCallGraph")
+	defer Log(ctx, scf).Trace().Msg("<<<< NewStaticCallGraph")
+
+	Log(ctx, scf).Debug().Str("algorithm", string(algorithm)).Msg("Instantiating new static call-graph")
+	return &StaticCallGraph{
+		workDir:      workDir,
+		overlay:      overlay,
+		algorithm:    algorithm,
+		funcsByLine:  make(map[string][]*types.Func),
+		identsByLine: make(map[string][]identObject),
+	}
+}
+
+func (s *StaticCallGraph) Initialize(ctx context.Context) error {
+	Log(ctx, scf).Trace().Msg(">>>> Initialize")
+	defer Log(ctx, scf).Trace().Msg("<<<< Initialize")
+
+	Log(ctx, scf).Debug().Str("workDir", s.workDir).Msg("Loading packages")
+	cfg := &packages.Config{
+		Mode:    packages.LoadAllSyntax,
+		Dir:     s.workDir,
+		Overlay: s.overlay,
+	}
+	initial, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return fmt.Errorf("#Initialize: failed to load packages -> %w", err)
+	}
+
+	if count := packages.PrintErrors(initial); count > 0 {
+		Log(ctx, scf).Warn().Int("errors", count).Msg("Packages loaded with errors, call-graph may be incomplete")
+	}
+
+	Log(ctx, scf).Debug().Msg("Building SSA program")
+	prog, _ := ssautil.AllPackages(initial, ssa.InstantiateGenerics)
+	prog.Build()
+
+	Log(ctx, scf).Debug().Str("algorithm", string(s.algorithm)).Msg("Constructing call-graph")
+	switch s.algorithm {
+	case CHA:
+		s.graph = cha.CallGraph(prog)
+	case VTA:
+		s.graph = vta.CallGraph(ssautil.AllFunctions(prog), cha.CallGraph(prog))
+	default:
+		return fmt.Errorf("#Initialize: unknown call-graph algorithm %q", s.algorithm)
+	}
+
+	s.fset = prog.Fset
+	s.prog = prog
+	packages.Visit(initial, nil, func(pkg *packages.Package) {
+		s.pkgs = append(s.pkgs, pkg)
+		for _, obj := range pkg.TypesInfo.Defs {
+			if fn, ok := obj.(*types.Func); ok {
+				key := s.lineKey(s.fset.Position(fn.Pos()))
+				s.funcsByLine[key] = append(s.funcsByLine[key], fn)
+			}
+		}
+	})
+
+	s.workspace = initial
+	for _, pkg := range initial {
+		for _, idents := range []map[*ast.Ident]types.Object{pkg.TypesInfo.Defs, pkg.TypesInfo.Uses} {
+			for ident, obj := range idents {
+				if obj == nil {
+					continue
+				}
+				key := s.lineKey(s.fset.Position(ident.Pos()))
+				s.identsByLine[key] = append(s.identsByLine[key], identObject{ident: ident, obj: obj})
+			}
+		}
+	}
+
+	Log(ctx, scf).Info().Int("functions", len(s.graph.Nodes)).Msg("Static call-graph is ready")
+	return nil
+}
+
+func (s *StaticCallGraph) OutgoingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyOutgoingCallResponse {
+	Log(ctx, scf).Trace().Msg(">>>> OutgoingCalls")
+	defer Log(ctx, scf).Trace().Msg("<<<< OutgoingCalls")
+
+	responseChan := make(chan *CallHierarchyOutgoingCallResponse, 1)
+
+	fn := s.funcAt(filePath, line, character)
+	if fn == nil {
+		responseChan <- &CallHierarchyOutgoingCallResponse{
+			Error: &ResponseError{
+				Code:    EmptyCallHierarchPrepareResponse,
+				Message: fmt.Sprintf("OutgoingCalls: no function declared at %s:%d:%d", filePath, line, character),
+			},
+		}
+		return responseChan
+	}
+
+	// Interface methods don't have a body, hence no outgoing calls, same as gopls.
+	response := &CallHierarchyOutgoingCallResponse{Result: make([]CallHierarchyOutgoingCall, 0)}
+	ssaFn := s.prog.FuncValue(fn)
+	if ssaFn == nil {
+		responseChan <- response
+		return responseChan
+	}
+
+	// gopls attributes calls made inside function literals to the enclosing declaration,
+	// hence walking the anonymous functions as well.
+	fromRanges := make(map[*types.Func][]Range)
+	var collect func(caller *ssa.Function)
+	collect = func(caller *ssa.Function) {
+		if node := s.graph.Nodes[caller]; node != nil {
+			for _, edge := range node.Out {
+				callee, ok := edge.Callee.Func.Object().(*types.Func)
+				if !ok {
+					continue
+				}
+				fromRanges[callee] = append(fromRanges[callee], s.rangeOf(edge.Pos(), 0))
+			}
+		}
+		for _, anon := range caller.AnonFuncs {
+			collect(anon)
+		}
+	}
+	collect(ssaFn)
+
+	for callee, ranges := range fromRanges {
+		response.Result = append(response.Result, CallHierarchyOutgoingCall{
+			To:         s.callHierarchyItem(callee),
+			FromRanges: ranges,
+		})
+	}
+	sort.Slice(response.Result, func(i, j int) bool {
+		a, b := response.Result[i].To, response.Result[j].To
+		if a.Uri != b.Uri {
+			return a.Uri < b.Uri
+		}
+		return a.Range.Start.Line < b.Range.Start.Line
+	})
+
+	responseChan <- response
+	return responseChan
+}
+
+func (s *StaticCallGraph) IncomingCalls(ctx context.Context, filePath string, line, character int) chan *CallHierarchyIncomingCallResponse {
+	Log(ctx, scf).Trace().Msg(">>>> IncomingCalls")
+	defer Log(ctx, scf).Trace().Msg("<<<< IncomingCalls")
+
+	responseChan := make(chan *CallHierarchyIncomingCallResponse, 1)
+
+	fn := s.funcAt(filePath, line, character)
+	if fn == nil {
+		responseChan <- &CallHierarchyIncomingCallResponse{
+			Error: &ResponseError{
+				Code:    EmptyCallHierarchPrepareResponse,
+				Message: fmt.Sprintf("IncomingCalls: no function declared at %s:%d:%d", filePath, line, character),
+			},
+		}
+		return responseChan
+	}
+
+	// Calls made inside function literals are attributed to the enclosing declaration, same as gopls.
+	fromRanges := make(map[*types.Func][]Range)
+	add := func(caller *ssa.Function, pos token.Pos) {
+		for caller.Parent() != nil {
+			caller = caller.Parent()
+		}
+		if callerFn, ok := caller.Object().(*types.Func); ok {
+			fromRanges[callerFn] = append(fromRanges[callerFn], s.rangeOf(pos, 0))
+		}
+	}
+
+	if ssaFn := s.prog.FuncValue(fn); ssaFn != nil {
+		if node := s.graph.Nodes[ssaFn]; node != nil {
+			for _, edge := range node.In {
+				add(edge.Caller.Func, edge.Pos())
+			}
+		}
+	} else {
+		// Interface methods don't have a node, their callers are the dynamic call sites invoking them.
+		// Such a site has an edge per implementation, hence counting each site once.
+		seen := make(map[ssa.CallInstruction]struct{})
+		for _, node := range s.graph.Nodes {
+			for _, edge := range node.Out {
+				if edge.Site == nil || edge.Site.Common().Method != fn {
+					continue
+				}
+				if _, ok := seen[edge.Site]; ok {
+					continue
+				}
+				seen[edge.Site] = struct{}{}
+				add(edge.Caller.Func, edge.Pos())
+			}
+		}
+	}
+
+	response := &CallHierarchyIncomingCallResponse{Result: make([]CallHierarchyIncomingCall, 0)}
+	for caller, ranges := range fromRanges {
+		response.Result = append(response.Result, CallHierarchyIncomingCall{
+			From:       s.callHierarchyItem(caller),
+			FromRanges: ranges,
+		})
+	}
+	sort.Slice(response.Result, func(i, j int) bool {
+		a, b := response.Result[i].From, response.Result[j].From
+		if a.Uri != b.Uri {
+			return a.Uri < b.Uri
+		}
+		return a.Range.Start.Line < b.Range.Start.Line
+	})
+
+	responseChan <- response
+	return responseChan
+}
+
+func (s *StaticCallGraph) Implementations(ctx context.Context, filePath string, line, character int) chan *ImplementationResponse {
+	Log(ctx, scf).Trace().Msg(">>>> Implementations")
+	defer Log(ctx, scf).Trace().Msg("<<<< Implementations")
+
+	responseChan := make(chan *ImplementationResponse, 1)
+
+	fn := s.funcAt(filePath, line, character)
+	if fn == nil {
+		responseChan <- &ImplementationResponse{
+			Error: &ResponseError{
+				Code:    InvalidParams,
+				Message: fmt.Sprintf("Implementations: no function declared at %s:%d:%d", filePath, line, character),
+			},
+		}
+		return responseChan
+	}
+
+	response := &ImplementationResponse{Result: make([]Location, 0)}
+	recv := fn.Type().(*types.Signature).Recv()
+	if recv == nil {
+		responseChan <- response
+		return responseChan
+	}
+
+	// Same as gopls, an interface method yields the concrete methods implementing it,
+	// and a concrete method yields the interface methods it satisfies.
+	iface, isInterface := recv.Type().Underlying().(*types.Interface)
+	for _, named := range s.namedTypes() {
+		if _, ok := named.Underlying().(*types.Interface); ok == isInterface {
+			continue
+		}
+
+		var impl *types.Func
+		if isInterface {
+			if !types.Implements(named, iface) && !types.Implements(types.NewPointer(named), iface) {
+				continue
+			}
+			impl = s.lookupMethod(types.NewPointer(named), fn)
+		} else {
+			other := named.Underlying().(*types.Interface)
+			if other.NumMethods() == 0 {
+				continue
+			}
+			if !types.Implements(recv.Type(), other) {
+				continue
+			}
+			impl = s.lookupMethod(named, fn)
+		}
+
+		if impl != nil && impl.Pos().IsValid() {
+			response.Result = append(response.Result, Location{
+				Uri:   fmt.Sprintf("file://%s", s.fset.Position(impl.Pos()).Filename),
+				Range: s.rangeOf(impl.Pos(), len(impl.Name())),
+			})
+		}
+	}
+
+	responseChan <- response
+	return responseChan
+}
+
+func (s *StaticCallGraph) References(ctx context.Context, filePath string, line int, character int) chan *ReferenceResponse {
+	responseChan := make(chan *ReferenceResponse, 1)
+	responseChan <- &ReferenceResponse{Error: notSupported("References")}
+	return responseChan
+}
+
+func (s *StaticCallGraph) Hover(ctx context.Context, filePath string, line, character int) chan *HoverResponse {
+	responseChan := make(chan *HoverResponse, 1)
+	responseChan <- &HoverResponse{Error: notSupported("Hover")}
+	return responseChan
+}
+
+func (s *StaticCallGraph) DocumentSymbol(ctx context.Context, filePath string) chan *DocumentSymbolResponse {
+	responseChan := make(chan *DocumentSymbolResponse, 1)
+	responseChan <- &DocumentSymbolResponse{Error: notSupported("DocumentSymbol")}
+	return responseChan
+}
+
+func (s *StaticCallGraph) Definition(ctx context.Context, filePath string, line, character int) chan *DefinitionResponse {
+	Log(ctx, scf).Trace().Msg(">>>> Definition")
+	defer Log(ctx, scf).Trace().Msg("<<<< Definition")
+
+	responseChan := make(chan *DefinitionResponse, 1)
+	response := &DefinitionResponse{Result: make(Locations, 0)}
+
+	// Builtins and the like have no position.
+	if obj := s.objectAt(filePath, line, character); obj != nil && obj.Pos().IsValid() {
+		response.Result = append(response.Result, s.location(obj))
+	}
+
+	responseChan <- response
+	return responseChan
+}
+
+func (s *StaticCallGraph) TypeDefinition(ctx context.Context, filePath string, line, character int) chan *TypeDefinitionResponse {
+	Log(ctx, scf).Trace().Msg(">>>> TypeDefinition")
+	defer Log(ctx, scf).Trace().Msg("<<<< TypeDefinition")
+
+	responseChan := make(chan *TypeDefinitionResponse, 1)
+	response := &TypeDefinitionResponse{Result: make(Locations, 0)}
+
+	obj := s.objectAt(filePath, line, character)
+	if obj == nil {
+		responseChan <- response
+		return responseChan
+	}
+
+	t := obj.Type()
+	for {
+		pointer, ok := t.(*types.Pointer)
+		if !ok {
+			break
+		}
+		t = pointer.Elem()
+	}
+
+	// Only named types have a declaration to jump to.
+	if named, ok := t.(interface{ Obj() *types.TypeName }); ok && named.Obj().Pos().IsValid() {
+		response.Result = append(response.Result, s.location(named.Obj()))
+	}
+
+	responseChan <- response
+	return responseChan
+}
+
+// WorkspaceSymbol matches the query, case-insensitively, within the package qualified names of the symbols
+// declared by the workspace packages, e.g. `api.OntapAPIREST.VolumeCreate`.
+func (s *StaticCallGraph) WorkspaceSymbol(ctx context.Context, query string) chan *WorkspaceSymbolResponse {
+	Log(ctx, scf).Trace().Msg(">>>> WorkspaceSymbol")
+	defer Log(ctx, scf).Trace().Msg("<<<< WorkspaceSymbol")
+
+	responseChan := make(chan *WorkspaceSymbolResponse, 1)
+	response := &WorkspaceSymbolResponse{Result: make([]SymbolInformation, 0)}
+
+	query = strings.ToLower(query)
+	add := func(name string, kind SymbolKind, obj types.Object) {
+		if !strings.Contains(strings.ToLower(name), query) {
+			return
+		}
+		response.Result = append(response.Result, SymbolInformation{
+			Name:          name,
+			Kind:          kind,
+			Location:      s.location(obj),
+			ContainerName: obj.Pkg().Path(),
+		})
+	}
+
+	for _, pkg := range s.workspace {
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			qualified := fmt.Sprintf("%s.%s", pkg.Types.Name(), name)
+			switch obj := obj.(type) {
+			case *types.Func:
+				add(qualified, SymbolKindFunction, obj)
+			case *types.Var:
+				add(qualified, SymbolKindVariable, obj)
+			case *types.Const:
+				add(qualified, SymbolKindConstant, obj)
+			case *types.TypeName:
+				named, ok := obj.Type().(*types.Named)
+				if !ok {
+					add(qualified, SymbolKindClass, obj)
+					continue
+				}
+
+				if iface, ok := named.Underlying().(*types.Interface); ok {
+					add(qualified, SymbolKindInterface, obj)
+					for i := 0; i < iface.NumExplicitMethods(); i++ {
+						method := iface.ExplicitMethod(i)
+						add(qualified+"."+method.Name(), SymbolKindMethod, method)
+					}
+					continue
+				}
+
+				kind := SymbolKindClass
+				if _, ok := named.Underlying().(*types.Struct); ok {
+					kind = SymbolKindStruct
+				}
+				add(qualified, kind, obj)
+				for i := 0; i < named.NumMethods(); i++ {
+					method := named.Method(i)
+					add(qualified+"."+method.Name(), SymbolKindMethod, method)
+				}
+			}
+		}
+	}
+
+	responseChan <- response
+	return responseChan
+}
+
+// Shutdown has nothing to release, the program is dropped along with the call-graph.
+func (s *StaticCallGraph) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// objectAt returns the object declared or used by the identifier at the given zero based position, nil otherwise.
+func (s *StaticCallGraph) objectAt(filePath string, line, character int) types.Object {
+	for _, identObject := range s.identsByLine[fmt.Sprintf("%s:%d", filePath, line)] {
+		column := s.fset.Position(identObject.ident.Pos()).Column - 1
+		if character >= column && character <= column+len(identObject.ident.Name) {
+			return identObject.obj
+		}
+	}
+	return nil
+}
+
+func (s *StaticCallGraph) location(obj types.Object) Location {
+	return Location{
+		Uri:   fmt.Sprintf("file://%s", s.fset.Position(obj.Pos()).Filename),
+		Range: s.rangeOf(obj.Pos(), len(obj.Name())),
+	}
+}
+
+// funcAt returns the function whose name is at the given zero based position, nil otherwise.
+func (s *StaticCallGraph) funcAt(filePath string, line, character int) *types.Func {
+	for _, fn := range s.funcsByLine[fmt.Sprintf("%s:%d", filePath, line)] {
+		column := s.fset.Position(fn.Pos()).Column - 1
+		if character >= column && character <= column+len(fn.Name()) {
+			return fn
+		}
+	}
+	return nil
+}
+
+func (s *StaticCallGraph) lineKey(position token.Position) string {
+	return fmt.Sprintf("%s:%d", position.Filename, position.Line-1)
+}
+
+func (s *StaticCallGraph) rangeOf(pos token.Pos, length int) Range {
+	position := s.fset.Position(pos)
+	start := Position{Line: position.Line - 1, Character: position.Column - 1}
+	end := Position{Line: start.Line, Character: start.Character + length}
+	return Range{Start: start, End: end}
+}
+
+func (s *StaticCallGraph) callHierarchyItem(fn *types.Func) CallHierarchyItem {
+	position := s.fset.Position(fn.Pos())
+	kind := SymbolKindFunction
+	if fn.Type().(*types.Signature).Recv() != nil {
+		kind = SymbolKindMethod
+	}
+
+	var pkgPath string
+	if fn.Pkg() != nil {
+		pkgPath = fn.Pkg().Path()
+	}
+
+	nameRange := s.rangeOf(fn.Pos(), len(fn.Name()))
+	return CallHierarchyItem{
+		Name:           fn.Name(),
+		Kind:           kind,
+		Detail:         fmt.Sprintf("%s • %s", pkgPath, filepath.Base(position.Filename)),
+		Uri:            fmt.Sprintf("file://%s", position.Filename),
+		Range:          nameRange,
+		SelectionRange: nameRange,
+	}
+}
+
+func (s *StaticCallGraph) namedTypes() []*types.Named {
+	var named []*types.Named
+	for _, pkg := range s.pkgs {
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			typeName, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || typeName.IsAlias() {
+				continue
+			}
+			if n, ok := typeName.Type().(*types.Named); ok && n.TypeParams() == nil {
+				named = append(named, n)
+			}
+		}
+	}
+	return named
+}
+
+func (s *StaticCallGraph) lookupMethod(t types.Type, fn *types.Func) *types.Func {
+	obj, _, _ := types.LookupFieldOrMethod(t, true, fn.Pkg(), fn.Name())
+	method, _ := obj.(*types.Func)
+	return method
+}
+
+func notSupported(method string) *ResponseError {
+	return &ResponseError{
+		Code:    MethodNotFound,
+		Message: fmt.Sprintf("%s: not supported by the static call-graph backend", method),
+	}
+}
@@ -0,0 +1,136 @@
+package static_test
+
+import (
+	"context"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/callgraph/static"
+)
+
+var _ = Describe("StaticCallGraph", func() {
+	var (
+		ctx       = context.Background()
+		workDir   string
+		filePath  string
+		callGraph *static.StaticCallGraph
+	)
+
+	// Zero based positions of the function names in testdata/testmod/testmod.go
+	const (
+		clientLine      = 2
+		interfaceDoLine = 3
+		restClientDo    = 8
+		helperLine      = 12
+		runLine         = 16
+		mainLine        = 23
+	)
+
+	calleeNames := func(response *requests.CallHierarchyOutgoingCallResponse) []string {
+		names := make([]string, 0)
+		for _, call := range response.Result {
+			names = append(names, call.To.Name)
+		}
+		return names
+	}
+
+	for _, algorithm := range []static.Algorithm{static.CHA, static.VTA} {
+		algorithm := algorithm
+		Context("with algorithm "+string(algorithm), func() {
+			BeforeEach(func() {
+				var err error
+				workDir, err = filepath.Abs("testdata/testmod")
+				Expect(err).ToNot(HaveOccurred())
+				filePath = filepath.Join(workDir, "testmod.go")
+
+				callGraph = static.NewStaticCallGraph(ctx, workDir, nil, algorithm)
+				Expect(callGraph.Initialize(ctx)).To(Succeed())
+			})
+
+			It("should return the callees of a function, including the ones inside closures", func() {
+				response := <-callGraph.OutgoingCalls(ctx, filePath, runLine, 5)
+				Expect(response.Error).To(BeNil())
+				Expect(calleeNames(response)).To(ConsistOf("Do", "helper"))
+
+				for _, call := range response.Result {
+					Expect(call.To.Uri).To(Equal("file://" + filePath))
+					Expect(call.To.Detail).To(HavePrefix("example.com/testmod"))
+				}
+			})
+
+			It("should return the position of the callee name", func() {
+				response := <-callGraph.OutgoingCalls(ctx, filePath, mainLine, 5)
+				Expect(response.Error).To(BeNil())
+				Expect(response.Result).To(HaveLen(1))
+				Expect(response.Result[0].To.Name).To(Equal("Run"))
+				Expect(response.Result[0].To.Range.Start).To(Equal(requests.Position{Line: runLine, Character: 5}))
+			})
+
+			It("should return no outgoing calls for an interface method", func() {
+				response := <-callGraph.OutgoingCalls(ctx, filePath, interfaceDoLine, 1)
+				Expect(response.Error).To(BeNil())
+				Expect(response.Result).To(BeEmpty())
+			})
+
+			It("should return the implementations of an interface method", func() {
+				response := <-callGraph.Implementations(ctx, filePath, interfaceDoLine, 1)
+				Expect(response.Error).To(BeNil())
+				Expect(response.Result).To(HaveLen(1))
+				Expect(response.Result[0].Range.Start).To(Equal(requests.Position{Line: restClientDo, Character: 21}))
+			})
+
+			It("should return the callers of a function, attributing closures to their enclosing function", func() {
+				response := <-callGraph.IncomingCalls(ctx, filePath, helperLine, 5)
+				Expect(response.Error).To(BeNil())
+
+				callers := make([]string, 0)
+				for _, call := range response.Result {
+					callers = append(callers, call.From.Name)
+				}
+				Expect(callers).To(ConsistOf("Do", "Run"))
+			})
+
+			It("should return the callers of an interface method", func() {
+				response := <-callGraph.IncomingCalls(ctx, filePath, interfaceDoLine, 1)
+				Expect(response.Error).To(BeNil())
+				Expect(response.Result).To(HaveLen(1))
+				Expect(response.Result[0].From.Name).To(Equal("Run"))
+			})
+
+			It("should return the definition of a used identifier", func() {
+				// return helper()
+				response := <-callGraph.Definition(ctx, filePath, restClientDo+1, 9)
+				Expect(response.Error).To(BeNil())
+				Expect(response.Result).To(ConsistOf(requests.Location{
+					Uri:   "file://" + filePath,
+					Range: requests.Range{Start: requests.Position{Line: helperLine, Character: 5}, End: requests.Position{Line: helperLine, Character: 11}},
+				}))
+			})
+
+			It("should return the type definition of a parameter", func() {
+				// func Run(c Client) string {
+				response := <-callGraph.TypeDefinition(ctx, filePath, runLine, 9)
+				Expect(response.Error).To(BeNil())
+				Expect(response.Result).To(HaveLen(1))
+				Expect(response.Result[0].Range.Start).To(Equal(requests.Position{Line: clientLine, Character: 5}))
+			})
+
+			It("should find methods by their qualified name", func() {
+				response := <-callGraph.WorkspaceSymbol(ctx, "restClient.Do")
+				Expect(response.Error).To(BeNil())
+				Expect(response.Result).To(HaveLen(1))
+				Expect(response.Result[0].Name).To(Equal("testmod.restClient.Do"))
+				Expect(response.Result[0].Kind).To(Equal(requests.SymbolKindMethod))
+				Expect(response.Result[0].Location.Range.Start).To(Equal(requests.Position{Line: restClientDo, Character: 21}))
+			})
+
+			It("should return an error when no function is declared at the position", func() {
+				response := <-callGraph.OutgoingCalls(ctx, filePath, 0, 0)
+				Expect(response.Error).ToNot(BeNil())
+			})
+		})
+	}
+})
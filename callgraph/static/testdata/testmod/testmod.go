@@ -0,0 +1,26 @@
+package testmod
+
+type Client interface {
+	Do() string
+}
+
+type restClient struct{}
+
+func (c *restClient) Do() string {
+	return helper()
+}
+
+func helper() string {
+	return "done"
+}
+
+func Run(c Client) string {
+	run := func() string {
+		return c.Do()
+	}
+	return run() + helper()
+}
+
+func Main() string {
+	return Run(&restClient{})
+}
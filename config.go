@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	. "github.com/theshashankpal/api-collector/traverser"
+)
+
+// configFile is the content of the -config file.
+type configFile struct {
+	Gopls GoplsSettings `json:"gopls"`
+	Roots rootsConfig   `json:"roots"`
+	Scope Scope         `json:"scope"`
+	// Operations are the packages of the REST clients and ZAPI requests, Trident's if unset.
+	Operations Operations `json:"operations"`
+}
+
+// rootsConfig are the patterns selecting the roots of the walk down, ontap_rest.go and ontap_zapi.go if unset.
+type rootsConfig struct {
+	REST Roots `json:"rest"`
+	ZAPI Roots `json:"zapi"`
+}
+
+// readConfigFile reads the -config file, its zero value if there is none.
+func readConfigFile() (configFile, error) {
+	var config configFile
+	if *configPath == "" {
+		return config, nil
+	}
+
+	content, err := os.ReadFile(*configPath)
+	if err != nil {
+		return config, fmt.Errorf("failed to read the config file %s -> %w", *configPath, err)
+	}
+
+	if err = json.Unmarshal(content, &config); err != nil {
+		return config, fmt.Errorf("failed to parse the config file %s -> %w", *configPath, err)
+	}
+	return config, nil
+}
+
+// traversalRoots takes the roots of the -config file, if any, then overrides them with the -*_roots flags set.
+func traversalRoots(config configFile) (Roots, Roots) {
+	restRoots, zapiRoots := DefaultRESTRoots, DefaultZAPIRoots
+	if len(config.Roots.REST) > 0 {
+		restRoots = config.Roots.REST
+	}
+	if len(config.Roots.ZAPI) > 0 {
+		zapiRoots = config.Roots.ZAPI
+	}
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "rest_roots":
+			restRoots = splitList(*restRootsFlag)
+		case "zapi_roots":
+			zapiRoots = splitList(*zapiRootsFlag)
+		}
+	})
+	return restRoots, zapiRoots
+}
+
+// traversalOperations takes the operation packages of the -config file, if any, then overrides them with the
+// -*_operations flags set. Unset, they are laid out in the module of -work_dir as in Trident.
+func traversalOperations(config configFile) (Operations, error) {
+	operations := config.Operations
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "rest_operations":
+			operations.REST = splitList(*restOperations)
+		case "zapi_operations":
+			operations.ZAPI = splitList(*zapiOperations)
+		}
+	})
+	if len(operations.REST) > 0 && len(operations.ZAPI) > 0 {
+		return operations, nil
+	}
+
+	modulePath, err := ModulePath(*workDir)
+	if err != nil {
+		return operations, fmt.Errorf("failed to read the module of the work directory, set -rest_operations and -zapi_operations instead -> %w", err)
+	}
+
+	defaultOperations := DefaultOperations(modulePath)
+	if len(operations.REST) == 0 {
+		operations.REST = defaultOperations.REST
+	}
+	if len(operations.ZAPI) == 0 {
+		operations.ZAPI = defaultOperations.ZAPI
+	}
+	return operations, nil
+}
+
+// traversalScope takes the scope of the -config file, if any, then overrides it with the -scope* flags set.
+// Without prefixes, walking down stays within the packages holding the operation packages, walking up within
+// the whole module of -work_dir.
+func traversalScope(config configFile, operations Operations) (Scope, error) {
+	scope := config.Scope
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "scope":
+			scope.Prefixes = splitList(*scopeFlag)
+		case "scope_exclude":
+			scope.Exclusions = splitList(*scopeExclude)
+		}
+	})
+	if len(scope.Prefixes) > 0 {
+		return scope, nil
+	}
+	if !*reverse {
+		scope.Prefixes = APIScope(operations).Prefixes
+		return scope, nil
+	}
+
+	modulePath, err := ModulePath(*workDir)
+	if err != nil {
+		return scope, fmt.Errorf("failed to read the module of the work directory, set -scope instead -> %w", err)
+	}
+
+	scope.Prefixes = ModuleScope(modulePath).Prefixes
+	return scope, nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+)
+
+// goplsSettings takes the gopls settings of the -config file, if any, then overrides them with the -gopls_* flags set.
+func goplsSettings(config configFile) (GoplsSettings, error) {
+	var err error
+	settings := config.Gopls
+
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "gopls_build_flags":
+			settings.BuildFlags = strings.Fields(*goplsBuildFlags)
+		case "gopls_env":
+			settings.Env = make(map[string]string)
+			for _, variable := range splitList(*goplsEnv) {
+				key, value, ok := strings.Cut(variable, "=")
+				if !ok {
+					err = fmt.Errorf("flag -gopls_env must be a comma separated list of KEY=VALUE, got %q", variable)
+					return
+				}
+				settings.Env[key] = value
+			}
+		case "gopls_directory_filters":
+			settings.DirectoryFilters = splitList(*goplsDirectoryFilters)
+		case "gopls_expand_workspace_to_module":
+			settings.ExpandWorkspaceToModule = goplsExpandWorkspaceToModule
+		}
+	})
+	return settings, err
+}
+
+// splitList splits the comma separated list, dropping the empty items.
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
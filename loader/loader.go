@@ -110,7 +110,7 @@ func (p *Package) NeedSyntax() {
 	for i, filename := range p.CompiledGoFiles {
 		go func(i int, filename string) {
 			defer wg.Done()
-			src, err := os.ReadFile(filename)
+			src, err := p.loader.readFile(filename)
 			if err != nil {
 				p.AddError(err)
 				return
@@ -315,6 +315,15 @@ func (l *loader) parseFile(filename string, src []byte) (*ast.File, error) {
 	return file, nil
 }
 
+// readFile reads the given file, preferring the contents in the
+// config's Overlay, if any, to the ones on disk, as go/packages does.
+func (l *loader) readFile(filename string) ([]byte, error) {
+	if src, ok := l.cfg.Overlay[filename]; ok {
+		return src, nil
+	}
+	return os.ReadFile(filename)
+}
+
 // LoadRoots loads the given "root" packages by path, transitively loading
 // and all imports as well.
 //
@@ -4,14 +4,21 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"net"
+	"io"
 	"os"
+	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	. "github.com/theshashankpal/api-collector/callgraph"
+	"github.com/theshashankpal/api-collector/callgraph/cache"
 	. "github.com/theshashankpal/api-collector/callgraph/lsp"
+	. "github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/callgraph/static"
 	. "github.com/theshashankpal/api-collector/logger"
+	"github.com/theshashankpal/api-collector/overlay"
 	. "github.com/theshashankpal/api-collector/traverser"
 	. "github.com/theshashankpal/api-collector/traverser/ast-traverser"
 )
@@ -22,10 +29,38 @@ var (
 	rest              = flag.Bool("rest", false, "Scrape REST api endpoints")
 	zapi              = flag.Bool("zapi", false, "Scrape ZAPI commands")
 	workDir           = flag.String("work_dir", "", "Absolute path of the root of the Trident")
-	goplsAddress      = flag.String("gopls", "", "Address where the GOPLS server is running")
+	goplsAddress      = flag.String("gopls", "", "Address where the GOPLS server is running, tcp or unix:<path>. If not set, gopls is spawned")
+	goplsBin          = flag.String("gopls_bin", "gopls", "GOPLS binary to spawn when -gopls isn't set")
+	configPath        = flag.String("config", "", "JSON config file, holding the gopls settings under \"gopls\", e.g. {\"gopls\": {\"buildFlags\": [\"-tags=e2e\"]}}, the roots under \"roots\", e.g. {\"roots\": {\"rest\": [\"storage_drivers/ontap/api/abstraction_rest.go\"]}}, the scope under \"scope\", e.g. {\"scope\": {\"prefixes\": [\"github.com/netapp/trident/storage_drivers/ontap/api\"], \"exclusions\": [\"github.com/netapp/trident/storage_drivers/ontap/api/azgo/mocks\"]}}, and the operation packages under \"operations\", e.g. {\"operations\": {\"rest\": [\"example.com/ontap/rest/client\"], \"zapi\": [\"example.com/ontap/azgo\"]}}. The -gopls_*, -*_roots, -scope* and -*_operations flags override it")
+	overlayPath       = flag.String("overlay", "", "Patch of -work_dir, as git diff writes it, or directory of modified files laid out as in -work_dir, analyzed on top of -work_dir without touching it")
+	backend           = flag.String("backend", "lsp", "Call-graph backend to use, either lsp or static")
+	reverse           = flag.Bool("reverse", false, "Walk up from the REST client operations and ZAPI commands to all their callers, instead of down from the roots")
+	strategy          = flag.String("strategy", "dfs", "Order of the walk down from the roots, either dfs or bfs. bfs gives the shortest call distance from every root to every API")
+	maxDepth          = flag.Int("max_depth", 0, "Stop the bfs walk this many calls away from the roots. 0 doesn't stop it")
+	restRootsFlag     = flag.String("rest_roots", "", "Roots of the walk down to the REST APIs, comma separated Go files, globs, packages or pkg.Type.Method symbols. Files, globs and package directories are relative to -work_dir. Defaults to storage_drivers/ontap/api/ontap_rest.go")
+	zapiRootsFlag     = flag.String("zapi_roots", "", "Roots of the walk down to the ZAPI commands, same as -rest_roots. Defaults to storage_drivers/ontap/api/ontap_zapi.go")
+	scopeFlag         = flag.String("scope", "", "Import path prefixes of the packages the walk stays within, comma separated. Defaults to the longest import path the operation packages share walking down, e.g. <module>/storage_drivers/ontap/api, <module> walking up, <module> being read from the go.mod of -work_dir")
+	scopeExclude      = flag.String("scope_exclude", "", "Import path prefixes of the packages left out of -scope, comma separated")
+	restOperations    = flag.String("rest_operations", "", "Import paths of the go-swagger client packages, comma separated, whose methods in *client.go files are the REST operations, packages below them included. Defaults to <module>/storage_drivers/ontap/api/rest/client")
+	zapiOperations    = flag.String("zapi_operations", "", "Import paths of the ZAPI request packages, comma separated, whose ExecuteUsing methods in api-*.go files are the ZAPI commands, packages below them included. Defaults to <module>/storage_drivers/ontap/api/azgo")
+	staticAlgorithm   = flag.String("static_algorithm", "cha", "Call-graph algorithm of the static backend, either cha or vta")
+	recordFile        = flag.String("record", "", "Record every LSP request and response to this file")
+	replayFile        = flag.String("replay", "", "Replay the LSP responses recorded with -record from this file, instead of running gopls")
+	lspConcurrency    = flag.Int("lsp_concurrency", 32, "Number of LSP requests kept in flight at once, per gopls session. 0 doesn't limit them")
+	lspPool           = flag.Int("lsp_pool", 1, "Number of gopls sessions to spread the LSP requests across, by file. Each one loads the whole workspace")
+	lspRetries        = flag.Int("lsp_retries", DefaultRetryPolicy.MaxRetries, "Retry an LSP request this many times, with exponential backoff, while gopls answers ContentModified or ServerCancelled")
+	lspReadyTimeout   = flag.Duration("lsp_ready_timeout", 10*time.Minute, "Give up when gopls hasn't loaded the workspace for this long. 0 waits forever")
+	lspReconnects     = flag.Int("lsp_reconnects", 3, "Reconnect to gopls this many times over the run when the connection drops, sending the requests in flight again")
+	lspTimeout        = flag.Duration("lsp_timeout", 2*time.Minute, "Give up on an LSP request, cancelling it, when unanswered for this long. 0 waits forever")
+	cacheFile         = flag.String("cache", "", "Persist the call-graph results to this file, and reuse them for unchanged files on the next run")
 	logLevel          = flag.String("log_level", "info", "Provide the level for logger, default is INFO")
 	restAPIOutputFile = flag.String("rest_out", "rest_apis.json", "Output file for REST APIs, json format")
 	zapiOutputFile    = flag.String("zapi_out", "zapi_commands.json", "Output file for ZAPI commands, json format")
+
+	goplsBuildFlags              = flag.String("gopls_build_flags", "", "Build flags gopls loads the packages with, space separated, e.g. -tags=e2e")
+	goplsEnv                     = flag.String("gopls_env", "", "Environment variables of the gopls builds, comma separated KEY=VALUE, e.g. GOFLAGS=-mod=vendor")
+	goplsDirectoryFilters        = flag.String("gopls_directory_filters", "", "Directories gopls includes or excludes, comma separated, e.g. -vendor,-**/mocks")
+	goplsExpandWorkspaceToModule = flag.Bool("gopls_expand_workspace_to_module", true, "Whether gopls covers the whole module of -work_dir, rather than -work_dir only")
 )
 
 func main() {
@@ -45,20 +80,110 @@ func main() {
 
 	workDirTraverser := getWorkDirTraverser(*workDir)
 
-	//Establish a TCP connection to gopls server
-	Log(ctx, m).Info().Msgf("Establishing a TCP connection to gopls server at %s", *goplsAddress)
-	conn, err := net.Dial("tcp", *goplsAddress)
+	var workDirOverlay overlay.Overlay
+	if *overlayPath != "" {
+		var err error
+		workDirOverlay, err = overlay.Load(*workDir, *overlayPath)
+		if err != nil {
+			Log(ctx, m).Error().Msg(err.Error())
+			return
+		}
+		Log(ctx, m).Info().Int("files", len(workDirOverlay)).Msgf("Analyzing the overlay %s on top of the work directory", *overlayPath)
+	}
+
+	// The -config file is read once, every setting it holds being overridden by its own flags.
+	fileConfig, err := readConfigFile()
 	if err != nil {
-		Log(ctx, m).Error().Msgf("Failed to establish a TCP connection to gopls server at %s", *goplsAddress)
+		Log(ctx, m).Error().Msg(err.Error())
 		return
 	}
-	defer conn.Close()
-	Log(ctx, m).Info().Msgf("Connection to gopls server established at %s", *goplsAddress)
+
+	restRoots, zapiRoots := traversalRoots(fileConfig)
+	if !*reverse {
+		Log(ctx, m).Info().Strs("restRoots", restRoots).Strs("zapiRoots", zapiRoots).Msg("Walking down from the roots")
+	}
+
+	operations, err := traversalOperations(fileConfig)
+	if err != nil {
+		Log(ctx, m).Error().Msg(err.Error())
+		return
+	}
+	Log(ctx, m).Info().Stringer("operations", operations).Msg("Looking for the operations of the packages")
+
+	scope, err := traversalScope(fileConfig, operations)
+	if err != nil {
+		Log(ctx, m).Error().Msg(err.Error())
+		return
+	}
+	Log(ctx, m).Info().Stringer("scope", scope).Msg("Staying within the scope")
 
 	// Creating call-graph
-	Log(ctx, m).Info().Msg("Creating a call-graph")
+	Log(ctx, m).Info().Str("backend", *backend).Msg("Creating a call-graph")
 	var callGraph CallGraph
-	callGraph = NewAbstractionLSP(ctx, conn, *workDir, "trident")
+	switch *backend {
+	case "static":
+		callGraph = static.NewStaticCallGraph(ctx, *workDir, workDirOverlay, static.Algorithm(*staticAlgorithm))
+	default:
+		settings, err := goplsSettings(fileConfig)
+		if err != nil {
+			Log(ctx, m).Error().Msg(err.Error())
+			return
+		}
+
+		conns := make([]io.ReadWriteCloser, 0, *lspPool)
+		for len(conns) < *lspPool {
+			conn, err := connectGopls(ctx)
+			if err != nil {
+				Log(ctx, m).Error().Msg(err.Error())
+				for _, conn = range conns {
+					_ = conn.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+
+		config := Config{
+			ReadyTimeout:   *lspReadyTimeout,
+			RequestTimeout: *lspTimeout,
+			Concurrency:    *lspConcurrency,
+			Retry:          DefaultRetryPolicy,
+			Dial:           connectGopls,
+			MaxReconnects:  *lspReconnects,
+			Settings:       settings,
+			Overlay:        workDirOverlay,
+		}
+		config.Retry.MaxRetries = *lspRetries
+		if *recordFile != "" {
+			Log(ctx, m).Info().Msgf("Recording LSP session to the file :%s", *recordFile)
+			file, err := os.Create(*recordFile)
+			if err != nil {
+				Log(ctx, m).Error().Msgf("Failed to create file %s", *recordFile)
+				return
+			}
+			defer file.Close()
+
+			config.Recorder = NewRecorder(file, *workDir)
+		}
+
+		if len(conns) == 1 {
+			callGraph = NewAbstractionLSP(ctx, conns[0], *workDir, "trident", config)
+		} else {
+			callGraph = NewShardedLSP(ctx, conns, *workDir, "trident", config)
+		}
+	}
+
+	var cachedCallGraph *cache.CachedCallGraph
+	if *cacheFile != "" {
+		Log(ctx, m).Info().Msgf("Caching call-graph results in the file :%s", *cacheFile)
+		cachedCallGraph = cache.NewCachedCallGraph(ctx, callGraph, *cacheFile, *workDir, workDirOverlay)
+		callGraph = cachedCallGraph
+	}
+	defer func() {
+		if err := callGraph.Shutdown(ctx); err != nil {
+			Log(ctx, m).Warn().Msg(err.Error())
+		}
+	}()
 
 	// Initialize call-graph
 	Log(ctx, m).Debug().Msg("Initializing call-graph instance")
@@ -72,7 +197,8 @@ func main() {
 	// Creating a traverser and initializing it.
 	Log(ctx, m).Info().Msg("Creating a new traverser")
 	var traverser Traverser
-	traverser = NewAstTraverser(workDirTraverser, callGraph, *rest, *zapi)
+	traverser = NewAstTraverser(workDirTraverser, workDirOverlay, callGraph, *rest, *zapi, *reverse, Strategy(*strategy), *maxDepth,
+		restRoots, zapiRoots, scope, operations)
 	Log(ctx, m).Info().Msg("Traverser created")
 
 	Log(ctx, m).Info().Msg("Initializing traverser")
@@ -84,6 +210,8 @@ func main() {
 		Str("workDir", *workDir).
 		Bool("zapi", *zapi).
 		Bool("rest", *rest).
+		Bool("reverse", *reverse).
+		Str("strategy", *strategy).
 		Msg("Traversing...")
 	restAPIsMapChan, zapiCommandsMapChan := traverser.Traverse(ctx)
 
@@ -134,6 +262,35 @@ func main() {
 	}
 
 	tempWg.Wait()
+
+	if cachedCallGraph != nil {
+		if err = cachedCallGraph.Save(ctx); err != nil {
+			Log(ctx, m).Error().Msg(err.Error())
+		}
+	}
+
+	if reporter, ok := callGraph.(Reporter); ok {
+		logSummary(ctx, reporter.Summary())
+	}
+}
+
+// logSummary logs the statistics of the run, in a stable order, and the compile errors of the analyzed tree.
+func logSummary(ctx context.Context, summary Summary) {
+	keys := make([]string, 0, len(summary.Counts))
+	for key := range summary.Counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	event := Log(ctx, m).Info()
+	for _, key := range keys {
+		event = event.Int(key, summary.Counts[key])
+	}
+	event.Msg("Run summary")
+
+	for _, compileError := range summary.CompileErrors {
+		Log(ctx, m).Warn().Str("error", compileError).Msg("Compile error in the analyzed tree, calls may be missing")
+	}
 }
 
 func printFlag(f *flag.Flag) {
@@ -151,6 +308,111 @@ func getWorkDirTraverser(path string) string {
 	return path
 }
 
+// ChainLink is a function of a call chain, its file relative to the work directory and its line one-based.
+type ChainLink struct {
+	FunctionName string `json:"function_name"`
+	File         string `json:"file"`
+	Line         int    `json:"line"`
+}
+
+// chainLink turns the functionID of a function into its link, the functionID itself if it can't be parsed.
+func chainLink(functionID string) ChainLink {
+	function, err := ParseFunctionID(functionID)
+	if err != nil {
+		return ChainLink{FunctionName: functionID}
+	}
+
+	filePath := function.FilePath
+	if relPath, err := filepath.Rel(*workDir, filePath); err == nil {
+		filePath = relPath
+	}
+	return ChainLink{FunctionName: function.FunctionName, File: filePath, Line: function.Line + 1}
+}
+
+// callerName turns the functionID of a caller into its name and position, relative to the work directory.
+func callerName(functionID string) string {
+	link := chainLink(functionID)
+	return fmt.Sprintf("%s %s:%d", link.FunctionName, link.File, link.Line)
+}
+
+// callChains turns the functionIDs of the chains into their links.
+func callChains(chains [][]string) [][]ChainLink {
+	var links [][]ChainLink
+	for _, chain := range chains {
+		chainLinks := make([]ChainLink, 0, len(chain))
+		for _, functionID := range chain {
+			chainLinks = append(chainLinks, chainLink(functionID))
+		}
+		links = append(links, chainLinks)
+	}
+	return links
+}
+
+// rootDistances keys the distances by the name of the roots, nil if there are none.
+func rootDistances(distances map[string]int) map[string]int {
+	if len(distances) == 0 {
+		return nil
+	}
+
+	roots := make(map[string]int, len(distances))
+	for root, distance := range distances {
+		roots[callerName(root)] = distance
+	}
+	return roots
+}
+
+// apisByRoot gathers the APIs every root reaches, sorted and once each, returning the roots sorted as well.
+func apisByRoot(findings map[string]Finding, api func(finding Finding) string) ([]string, map[string][]string) {
+	reached := make(map[string]map[string]struct{})
+	for _, finding := range findings {
+		for _, root := range finding.Roots {
+			if _, ok := reached[root]; !ok {
+				reached[root] = make(map[string]struct{})
+			}
+			reached[root][api(finding)] = struct{}{}
+		}
+	}
+
+	roots := make([]string, 0, len(reached))
+	apis := make(map[string][]string, len(reached))
+	for root, rootAPIs := range reached {
+		roots = append(roots, root)
+		for rootAPI := range rootAPIs {
+			apis[root] = append(apis[root], rootAPI)
+		}
+		sort.Strings(apis[root])
+	}
+	sort.Strings(roots)
+	return roots, apis
+}
+
+// connectGopls replays the session recorded in -replay, or dials the gopls server at -gopls,
+// or spawns -gopls_bin over stdio if no address is given.
+func connectGopls(ctx context.Context) (io.ReadWriteCloser, error) {
+	if *replayFile != "" {
+		Log(ctx, m).Info().Msgf("Replaying LSP session from the file :%s", *replayFile)
+		file, err := os.Open(*replayFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open file %s -> %w", *replayFile, err)
+		}
+		defer file.Close()
+		return NewReplayer(file, *workDir)
+	}
+
+	if *goplsAddress != "" {
+		Log(ctx, m).Info().Msgf("Establishing a connection to gopls server at %s", *goplsAddress)
+		conn, err := Dial(ctx, *goplsAddress)
+		if err != nil {
+			return nil, err
+		}
+		Log(ctx, m).Info().Msgf("Connection to gopls server established at %s", *goplsAddress)
+		return conn, nil
+	}
+
+	Log(ctx, m).Info().Msgf("Spawning gopls server using %s", *goplsBin)
+	return StartGopls(ctx, *goplsBin, *workDir)
+}
+
 func validateFlags() error {
 	if *rest == false && *zapi == false {
 		return fmt.Errorf("at least one of the flags -rest or -zapi must be set")
@@ -160,8 +422,39 @@ func validateFlags() error {
 		return fmt.Errorf("flag -work_dir must be set")
 	}
 
-	if *goplsAddress == "" {
-		return fmt.Errorf("flag -gopls must be set")
+	switch Strategy(*strategy) {
+	case DFS:
+	case BFS:
+		if *reverse {
+			return fmt.Errorf("flag -strategy=bfs only walks down, it can't be set with -reverse")
+		}
+	default:
+		return fmt.Errorf("flag -strategy must be either %s or %s", DFS, BFS)
+	}
+	if *maxDepth < 0 {
+		return fmt.Errorf("flag -max_depth can't be negative")
+	}
+	if *maxDepth != 0 && Strategy(*strategy) != BFS {
+		return fmt.Errorf("flag -max_depth only stops the bfs walk, it can't be set without -strategy=bfs")
+	}
+	if *reverse && (*restRootsFlag != "" || *zapiRootsFlag != "") {
+		return fmt.Errorf("flags -rest_roots and -zapi_roots only apply walking down, they can't be set with -reverse")
+	}
+
+	switch *backend {
+	case "lsp":
+		if *lspPool < 1 {
+			return fmt.Errorf("flag -lsp_pool must be at least 1")
+		}
+		if *recordFile != "" && *replayFile != "" {
+			return fmt.Errorf("flags -record and -replay can't be set together")
+		}
+	case "static":
+		if *staticAlgorithm != string(static.CHA) && *staticAlgorithm != string(static.VTA) {
+			return fmt.Errorf("flag -static_algorithm must be either %s or %s", static.CHA, static.VTA)
+		}
+	default:
+		return fmt.Errorf("flag -backend must be either lsp or static")
 	}
 
 	return nil
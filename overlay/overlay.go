@@ -0,0 +1,78 @@
+// Package overlay lets the analysis see changes which aren't on disk, such as the files of a patch not checked out
+// yet, or generated files. An Overlay maps the absolute path of every changed file to its content, as
+// packages.Config.Overlay does, so that it can be handed over to go/packages as it is, and opened in gopls.
+package overlay
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Overlay maps the absolute path of every file to the content replacing the one on disk.
+// The nil Overlay replaces nothing.
+type Overlay map[string][]byte
+
+// Load builds the overlay of path, which is either a directory of modified files laid out as in workDir,
+// or a patch of workDir, as FromDir and FromPatch do.
+func Load(workDir, path string) (Overlay, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("#Load: failed to stat %s -> %w", path, err)
+	}
+	if info.IsDir() {
+		return FromDir(workDir, path)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("#Load: failed to open %s -> %w", path, err)
+	}
+	defer file.Close()
+	return FromPatch(workDir, file)
+}
+
+// FromDir overlays every file of dir on the file at the same path relative to workDir.
+func FromDir(workDir, dir string) (Overlay, error) {
+	overlay := make(Overlay)
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() {
+			return err
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		overlay[filepath.Join(workDir, relPath)] = content
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("#FromDir: failed to read %s -> %w", dir, err)
+	}
+	return overlay, nil
+}
+
+// ReadFile returns the content of the file, from the overlay if it replaces it, from disk otherwise.
+func (o Overlay) ReadFile(path string) ([]byte, error) {
+	if content, ok := o[path]; ok {
+		return content, nil
+	}
+	return os.ReadFile(path)
+}
+
+// Files returns the paths of the files of the overlay, sorted.
+func (o Overlay) Files() []string {
+	files := make([]string, 0, len(o))
+	for file := range o {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+	return files
+}
@@ -0,0 +1,145 @@
+package overlay_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/overlay"
+)
+
+var _ = Describe("Overlay", func() {
+	const volumes = "package api\n\nfunc VolumeCreate() {\n\tcreate()\n}\n\nfunc VolumeDelete() {\n\tdelete()\n}\n"
+
+	var workDir string
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = os.MkdirTemp("", "overlay")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(os.MkdirAll(filepath.Join(workDir, "api"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workDir, "api", "volumes.go"), []byte(volumes), 0o644)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(workDir)).To(Succeed())
+	})
+
+	It("should apply a git diff on top of the work directory, adding the new files", func() {
+		patch := `diff --git a/api/volumes.go b/api/volumes.go
+index 1111111..2222222 100644
+--- a/api/volumes.go
++++ b/api/volumes.go
+@@ -3,3 +3,4 @@ package api
+ func VolumeCreate() {
+ 	create()
++	VolumeModify()
+ }
+@@ -8,2 +9,2 @@ func VolumeDelete() {
+-	delete()
++	destroy()
+ }
+diff --git a/api/modify.go b/api/modify.go
+new file mode 100644
+--- /dev/null
++++ b/api/modify.go
+@@ -0,0 +1,3 @@
++package api
++
++func VolumeModify() {}
+`
+		o, err := overlay.FromPatch(workDir, strings.NewReader(patch))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.Files()).To(Equal([]string{
+			filepath.Join(workDir, "api", "modify.go"),
+			filepath.Join(workDir, "api", "volumes.go"),
+		}))
+		Expect(string(o[filepath.Join(workDir, "api", "volumes.go")])).To(Equal(
+			"package api\n\nfunc VolumeCreate() {\n\tcreate()\n\tVolumeModify()\n}\n\nfunc VolumeDelete() {\n\tdestroy()\n}\n"))
+		Expect(string(o[filepath.Join(workDir, "api", "modify.go")])).To(Equal("package api\n\nfunc VolumeModify() {}\n"))
+
+		// The files on disk are left untouched.
+		content, err := os.ReadFile(filepath.Join(workDir, "api", "volumes.go"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal(volumes))
+	})
+
+	It("should honour files ending without a newline", func() {
+		patch := `--- api/volumes.go	2024-05-01 10:00:00
++++ api/volumes.go	2024-05-02 10:00:00
+@@ -9 +9 @@
+-}
++} // VolumeDelete
+\ No newline at end of file
+`
+		o, err := overlay.FromPatch(workDir, strings.NewReader(patch))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(o[filepath.Join(workDir, "api", "volumes.go")])).To(HaveSuffix("\tdelete()\n} // VolumeDelete"))
+	})
+
+	It("should refuse a patch which doesn't apply", func() {
+		patch := `diff --git a/api/volumes.go b/api/volumes.go
+--- a/api/volumes.go
++++ b/api/volumes.go
+@@ -4 +4 @@
+-	modify()
++	create()
+`
+		_, err := overlay.FromPatch(workDir, strings.NewReader(patch))
+		Expect(err).To(MatchError(ContainSubstring("hunk at line 4 doesn't apply")))
+	})
+
+	It("should keep the a/ and b/ directories of plain diffs, and carry renamed files over to their new path", func() {
+		Expect(os.MkdirAll(filepath.Join(workDir, "a"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(workDir, "a", "main.go"), []byte("package a\n"), 0o644)).To(Succeed())
+
+		patch := `--- a/main.go
++++ a/main.go
+@@ -1 +1,2 @@
+ package a
++// Package a
+diff --git a/api/volumes.go b/api/storage.go
+similarity index 100%
+rename from api/volumes.go
+rename to api/storage.go
+`
+		o, err := overlay.FromPatch(workDir, strings.NewReader(patch))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.Files()).To(Equal([]string{filepath.Join(workDir, "a", "main.go"), filepath.Join(workDir, "api", "storage.go")}))
+		Expect(string(o[filepath.Join(workDir, "a", "main.go")])).To(Equal("package a\n// Package a\n"))
+		Expect(string(o[filepath.Join(workDir, "api", "storage.go")])).To(Equal(volumes))
+	})
+
+	It("should refuse binary patches", func() {
+		patch := `diff --git a/api/logo.png b/api/logo.png
+index 1111111..2222222 100644
+Binary files a/api/logo.png and b/api/logo.png differ
+`
+		_, err := overlay.FromPatch(workDir, strings.NewReader(patch))
+		Expect(err).To(MatchError(ContainSubstring("binary patch of api/logo.png isn't supported")))
+	})
+
+	It("should overlay the files of a directory on the work directory, reading the others from disk", func() {
+		dir, err := os.MkdirTemp("", "modified")
+		Expect(err).ToNot(HaveOccurred())
+		defer os.RemoveAll(dir)
+		Expect(os.MkdirAll(filepath.Join(dir, "api"), 0o755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(dir, "api", "volumes.go"), []byte("package api\n"), 0o644)).To(Succeed())
+
+		o, err := overlay.Load(workDir, dir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(o.Files()).To(Equal([]string{filepath.Join(workDir, "api", "volumes.go")}))
+
+		content, err := o.ReadFile(filepath.Join(workDir, "api", "volumes.go"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("package api\n"))
+
+		Expect(os.WriteFile(filepath.Join(workDir, "api", "other.go"), []byte("package other\n"), 0o644)).To(Succeed())
+		content, err = o.ReadFile(filepath.Join(workDir, "api", "other.go"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(content)).To(Equal("package other\n"))
+	})
+})
@@ -0,0 +1,244 @@
+package overlay
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// filePatch holds the hunks of one file of a patch. Its paths are relative, empty for /dev/null.
+type filePatch struct {
+	oldPath string
+	newPath string
+	hunks   []hunk
+}
+
+// hunk holds the lines of one @@ section, each starting with ' ', '-' or '+', and ending with its newline
+// unless it is the last line of a file ending without one.
+type hunk struct {
+	oldStart int
+	oldCount int
+	lines    []string
+}
+
+// FromPatch applies the patch on top of the files of workDir. The patch is in the unified format of diff -u
+// and git diff, its paths relative to workDir, git diff ones with or without its a/ and b/ prefixes.
+// The files it deletes are left as they are, an overlay can only add or replace files, hence a renamed
+// file is found at both its old and new paths. Binary patches aren't supported.
+func FromPatch(workDir string, patch io.Reader) (Overlay, error) {
+	files, err := parsePatch(patch)
+	if err != nil {
+		return nil, fmt.Errorf("#FromPatch: failed to parse the patch -> %w", err)
+	}
+
+	overlay := make(Overlay)
+	for _, file := range files {
+		if file.newPath == "" {
+			continue
+		}
+
+		var original []byte
+		if file.oldPath != "" {
+			// Read through the overlay, in case the patch changes the same file more than once.
+			if original, err = overlay.ReadFile(filepath.Join(workDir, file.oldPath)); err != nil {
+				return nil, fmt.Errorf("#FromPatch: failed to read %s -> %w", file.oldPath, err)
+			}
+		}
+
+		content, err := file.apply(string(original))
+		if err != nil {
+			return nil, fmt.Errorf("#FromPatch: failed to patch %s -> %w", file.newPath, err)
+		}
+		overlay[filepath.Join(workDir, file.newPath)] = []byte(content)
+	}
+	return overlay, nil
+}
+
+func parsePatch(patch io.Reader) ([]*filePatch, error) {
+	content, err := io.ReadAll(patch)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []*filePatch
+	lines := strings.SplitAfter(string(content), "\n")
+	// gitFile is the file of the last diff --git header, until its ---/+++ header or its first hunk.
+	var gitFile *filePatch
+	for i := 0; i < len(lines); i++ {
+		switch line := lines[i]; {
+		case strings.HasPrefix(line, "diff --git "):
+			oldPath, newPath, err := parseGitHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("%w, at line %d", err, i+1)
+			}
+			gitFile = &filePatch{oldPath: oldPath, newPath: newPath}
+			files = append(files, gitFile)
+		case gitFile != nil && strings.HasPrefix(line, "new file mode "):
+			gitFile.oldPath = ""
+		case gitFile != nil && strings.HasPrefix(line, "deleted file mode "):
+			gitFile.newPath = ""
+		case gitFile != nil && (strings.HasPrefix(line, "rename from ") || strings.HasPrefix(line, "copy from ")):
+			_, gitFile.oldPath, _ = strings.Cut(strings.TrimRight(line, "\r\n"), " from ")
+		case gitFile != nil && (strings.HasPrefix(line, "rename to ") || strings.HasPrefix(line, "copy to ")):
+			_, gitFile.newPath, _ = strings.Cut(strings.TrimRight(line, "\r\n"), " to ")
+		case gitFile != nil && (strings.HasPrefix(line, "Binary files ") || strings.HasPrefix(line, "GIT binary patch")):
+			return nil, fmt.Errorf("binary patch of %s isn't supported, at line %d", gitFile.newPath, i+1)
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			if gitFile != nil {
+				// The paths of the git header already hold, those of ---/+++ only being there with hunks.
+				gitFile.oldPath, gitFile.newPath = patchPath(line, "a/"), patchPath(lines[i+1], "b/")
+			} else {
+				files = append(files, &filePatch{oldPath: patchPath(line, ""), newPath: patchPath(lines[i+1], "")})
+			}
+			gitFile = nil
+			i++
+		case strings.HasPrefix(line, "@@ "):
+			gitFile = nil
+			if len(files) == 0 {
+				return nil, fmt.Errorf("hunk before any file header, at line %d", i+1)
+			}
+			h, newCount, err := parseHunkHeader(line)
+			if err != nil {
+				return nil, fmt.Errorf("%w, at line %d", err, i+1)
+			}
+
+			// Read the lines of the hunk, and the "\ No newline at end of file" marker following the last one.
+			oldCount := h.oldCount
+			for i+1 < len(lines) && (oldCount > 0 || newCount > 0 || strings.HasPrefix(lines[i+1], `\`)) {
+				i++
+				line = lines[i]
+				if strings.HasPrefix(line, `\`) {
+					if len(h.lines) > 0 {
+						h.lines[len(h.lines)-1] = strings.TrimSuffix(h.lines[len(h.lines)-1], "\n")
+					}
+					continue
+				}
+
+				// Some editors strip the space of the empty context lines.
+				if line == "\n" {
+					line = " \n"
+				}
+				switch {
+				case strings.HasPrefix(line, " "):
+					oldCount--
+					newCount--
+				case strings.HasPrefix(line, "-"):
+					oldCount--
+				case strings.HasPrefix(line, "+"):
+					newCount--
+				default:
+					return nil, fmt.Errorf("unexpected line in hunk, at line %d", i+1)
+				}
+				h.lines = append(h.lines, line)
+			}
+			if oldCount != 0 || newCount != 0 {
+				return nil, fmt.Errorf("hunk doesn't match its header, at line %d", i+1)
+			}
+
+			file := files[len(files)-1]
+			file.hunks = append(file.hunks, h)
+		}
+	}
+	return files, nil
+}
+
+// patchPath returns the path of a ---/+++ header line, without its git prefix if any, empty for /dev/null.
+func patchPath(line string, prefix string) string {
+	path := strings.TrimRight(line[len("--- "):], "\r\n")
+	// diff -u follows the path with a tab and the modification time.
+	path, _, _ = strings.Cut(path, "\t")
+	if path == "/dev/null" {
+		return ""
+	}
+	if prefix != "" {
+		path = strings.TrimPrefix(path, prefix)
+	}
+	return path
+}
+
+// parseGitHeader returns the old and new paths of "diff --git a/old b/new", without their prefixes if any.
+func parseGitHeader(line string) (string, string, error) {
+	paths := strings.TrimRight(line[len("diff --git "):], "\r\n")
+	// The paths can't be told apart if they hold spaces, unless prefixed, or the same.
+	if oldPath, newPath, found := strings.Cut(paths, " b/"); found && strings.HasPrefix(oldPath, "a/") {
+		return oldPath[len("a/"):], newPath, nil
+	}
+	if half := len(paths) / 2; len(paths)%2 == 1 && paths[half] == ' ' && paths[:half] == paths[half+1:] {
+		return paths[:half], paths[half+1:], nil
+	}
+	return "", "", fmt.Errorf("malformed git header %q", strings.TrimSpace(line))
+}
+
+// parseHunkHeader parses "@@ -oldStart,oldCount +newStart,newCount @@", a missing count being 1.
+func parseHunkHeader(line string) (hunk, int, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return hunk{}, 0, fmt.Errorf("malformed hunk header %q", strings.TrimSpace(line))
+	}
+
+	oldStart, oldCount, err := parseRange(fields[1][1:])
+	if err != nil {
+		return hunk{}, 0, fmt.Errorf("malformed hunk header %q -> %w", strings.TrimSpace(line), err)
+	}
+	_, newCount, err := parseRange(fields[2][1:])
+	if err != nil {
+		return hunk{}, 0, fmt.Errorf("malformed hunk header %q -> %w", strings.TrimSpace(line), err)
+	}
+	return hunk{oldStart: oldStart, oldCount: oldCount}, newCount, nil
+}
+
+func parseRange(r string) (int, int, error) {
+	start, count, found := strings.Cut(r, ",")
+	startLine, err := strconv.Atoi(start)
+	if err != nil || !found {
+		return startLine, 1, err
+	}
+	lineCount, err := strconv.Atoi(count)
+	return startLine, lineCount, err
+}
+
+// apply applies the hunks, in order, on the original content, checking that the lines they remove or keep
+// are the ones of the original.
+func (f *filePatch) apply(original string) (string, error) {
+	lines := strings.SplitAfter(original, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	var patched strings.Builder
+	next := 0
+	for _, h := range f.hunks {
+		// A hunk removing nothing inserts its lines after oldStart, the others start at oldStart.
+		start := h.oldStart - 1
+		if h.oldCount == 0 {
+			start = h.oldStart
+		}
+		if start < next || start > len(lines) {
+			return "", fmt.Errorf("hunk at line %d is out of order or past the end of the file", h.oldStart)
+		}
+		for ; next < start; next++ {
+			patched.WriteString(lines[next])
+		}
+
+		for _, line := range h.lines {
+			switch line[0] {
+			case ' ', '-':
+				if next >= len(lines) || lines[next] != line[1:] {
+					return "", fmt.Errorf("hunk at line %d doesn't apply, line %d differs", h.oldStart, next+1)
+				}
+				next++
+				if line[0] == ' ' {
+					patched.WriteString(line[1:])
+				}
+			case '+':
+				patched.WriteString(line[1:])
+			}
+		}
+	}
+	for ; next < len(lines); next++ {
+		patched.WriteString(lines[next])
+	}
+	return patched.String(), nil
+}
@@ -4,8 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	. "github.com/theshashankpal/api-collector/logger"
+	. "github.com/theshashankpal/api-collector/traverser"
 	"os"
-	"strings"
 )
 
 var rst = LogFields{Key: "layer", Value: "rest"}
@@ -14,29 +14,54 @@ type RestAPIs struct {
 	FunctionName string `json:"function_name"`
 	API          string `json:"api"`
 	Method       string `json:"method"`
+	// Chains lead from the roots down to the function, they aren't found with -reverse.
+	Chains [][]ChainLink `json:"chains,omitempty"`
+	// Callers are only found with -reverse.
+	Callers []string `json:"callers,omitempty"`
+	// Roots are only found with -strategy=bfs, along with their shortest call distance to the API.
+	Roots map[string]int `json:"roots,omitempty"`
+}
+
+// RootRESTAPIs are the REST APIs a root reaches, each one as its method followed by its path pattern.
+type RootRESTAPIs struct {
+	Root ChainLink `json:"root"`
+	APIs []string  `json:"apis"`
 }
 
 type RestAPIsList struct {
 	APIs []RestAPIs `json:"apis"`
+	// ByRoot are the APIs of every root, they aren't found with -reverse.
+	ByRoot []RootRESTAPIs `json:"by_root,omitempty"`
 }
 
-func WriteRESTAPIs(ctx context.Context, restAPIsMap map[string][]string, file *os.File) error {
+func WriteRESTAPIs(ctx context.Context, restAPIsMap map[string]Finding, file *os.File) error {
 	// Write REST APIs to a file
 
 	restAPIsList := RestAPIsList{
 		APIs: make([]RestAPIs, 0),
 	}
 
-	for key, value := range restAPIsMap {
-		functionName := strings.TrimSpace(strings.Split(key, ":")[3])
+	for key, finding := range restAPIsMap {
 		tempRestAPIs := RestAPIs{
-			FunctionName: functionName,
-			Method:       value[0],
-			API:          value[1],
+			FunctionName: chainLink(key).FunctionName,
+			Method:       finding.API[0],
+			API:          finding.API[1],
+			Chains:       callChains(finding.Chains),
+			Roots:        rootDistances(finding.Distances),
+		}
+		for _, caller := range finding.Callers {
+			tempRestAPIs.Callers = append(tempRestAPIs.Callers, callerName(caller))
 		}
 		restAPIsList.APIs = append(restAPIsList.APIs, tempRestAPIs)
 	}
 
+	roots, apis := apisByRoot(restAPIsMap, func(finding Finding) string {
+		return finding.API[0] + " " + finding.API[1]
+	})
+	for _, root := range roots {
+		restAPIsList.ByRoot = append(restAPIsList.ByRoot, RootRESTAPIs{Root: chainLink(root), APIs: apis[root]})
+	}
+
 	jsonData, err := json.MarshalIndent(restAPIsList, "", "    ")
 	if err != nil {
 		return err
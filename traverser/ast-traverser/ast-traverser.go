@@ -2,51 +2,106 @@ package ast_traverser
 
 import (
 	"context"
-	"sync"
 
 	. "github.com/theshashankpal/api-collector/callgraph"
 	. "github.com/theshashankpal/api-collector/logger"
+	"github.com/theshashankpal/api-collector/overlay"
+	. "github.com/theshashankpal/api-collector/traverser"
+	. "github.com/theshashankpal/api-collector/traverser/ast-traverser/bfs"
 	. "github.com/theshashankpal/api-collector/traverser/ast-traverser/dfs"
 )
 
 var tf = LogFields{Key: "layer", Value: "traverser"}
 
+// Strategy is the order in which the call-graph is walked down from the roots.
+type Strategy string
+
+const (
+	// DFS goes as deep as it can first, visiting every function once whichever root reaches it.
+	DFS Strategy = "dfs"
+	// BFS goes one call deeper at a time, giving the shortest call distance from every root to every API.
+	BFS Strategy = "bfs"
+)
+
 // Search in an interface which will be implemented by DfsTraverser/BfsTraverser.
 type Search interface {
 	Initialize(ctx context.Context, done chan bool)
-	Traverse(ctx context.Context, mapChan chan map[string][]string)
+	Traverse(ctx context.Context, mapChan chan map[string]Finding)
 }
 
 type AstTraverser struct {
 	workDir       string
+	overlay       overlay.Overlay
 	callGraph     CallGraph
 	zapi          bool
 	rest          bool
+	reverse       bool
+	strategy      Strategy
+	maxDepth      int
+	restRoots     Roots
+	zapiRoots     Roots
+	scope         Scope
+	operations    Operations
 	restTraverser Search
 	zapiTraverser Search
 }
 
-func NewAstTraverser(workDir string, callGraph CallGraph, rest bool, zapi bool) *AstTraverser {
+// NewAstTraverser walks down from the functions restRoots and zapiRoots select to the REST APIs and ZAPI commands,
+// or, if reverse is set, walks up from the REST client operations and ZAPI commands to every caller. The packages
+// are loaded with the overlay on top of them. Walking down follows the strategy, BFS stopping maxDepth calls away
+// from the roots unless zero. Either way, the walk stays within the scope, the operations telling the functions
+// which issue the ONTAP calls.
+func NewAstTraverser(workDir string, overlay overlay.Overlay, callGraph CallGraph, rest bool, zapi bool, reverse bool,
+	strategy Strategy, maxDepth int, restRoots Roots, zapiRoots Roots, scope Scope, operations Operations) *AstTraverser {
 	return &AstTraverser{
-		workDir:   workDir,
-		callGraph: callGraph,
-		rest:      rest,
-		zapi:      zapi,
+		workDir:    workDir,
+		overlay:    overlay,
+		callGraph:  callGraph,
+		rest:       rest,
+		zapi:       zapi,
+		reverse:    reverse,
+		strategy:   strategy,
+		maxDepth:   maxDepth,
+		restRoots:  restRoots,
+		zapiRoots:  zapiRoots,
+		scope:      scope,
+		operations: operations,
 	}
 }
 
 func (t *AstTraverser) Initialize(ctx context.Context) {
 
-	// Because we are using the same callGraph for both REST and ZAPI recursers, we need to lock it
-	callGraphMU := new(sync.Mutex)
-	if t.rest {
-		Log(ctx, tf).Debug().Msg("Creating a new REST recurser")
-		t.restTraverser = NewDfsTraverser(t.callGraph, callGraphMU, t.workDir, RESTRecurserType)
+	// The same callGraph is shared by the REST and ZAPI recursers, it is safe for concurrent use.
+	restRecurserType, zapiRecurserType := RESTRecurserType, ZAPIRecurserType
+	if t.reverse {
+		restRecurserType, zapiRecurserType = RESTCallersRecurserType, ZAPICallersRecurserType
 	}
 
-	if t.zapi {
-		Log(ctx, tf).Debug().Msg("Creating a new ZAPI recurser")
-		t.zapiTraverser = NewDfsTraverser(t.callGraph, callGraphMU, t.workDir, ZAPIRecurserType)
+	switch {
+	case t.strategy == BFS && !t.reverse:
+		if t.rest {
+			Log(ctx, tf).Debug().Msgf("Creating a new %s BfsTraverser", RESTTarget)
+			t.restTraverser = NewBfsTraverser(t.callGraph, t.workDir, t.overlay, RESTTarget, t.restRoots, t.scope, t.operations,
+				t.maxDepth)
+		}
+
+		if t.zapi {
+			Log(ctx, tf).Debug().Msgf("Creating a new %s BfsTraverser", ZAPITarget)
+			t.zapiTraverser = NewBfsTraverser(t.callGraph, t.workDir, t.overlay, ZAPITarget, t.zapiRoots, t.scope, t.operations,
+				t.maxDepth)
+		}
+	default:
+		if t.rest {
+			Log(ctx, tf).Debug().Msgf("Creating a new %s", restRecurserType)
+			t.restTraverser = NewDfsTraverser(t.callGraph, t.workDir, t.overlay, restRecurserType, t.restRoots, t.scope,
+				t.operations)
+		}
+
+		if t.zapi {
+			Log(ctx, tf).Debug().Msgf("Creating a new %s", zapiRecurserType)
+			t.zapiTraverser = NewDfsTraverser(t.callGraph, t.workDir, t.overlay, zapiRecurserType, t.zapiRoots, t.scope,
+				t.operations)
+		}
 	}
 
 	var (
@@ -75,9 +130,9 @@ func (t *AstTraverser) Initialize(ctx context.Context) {
 	Log(ctx, tf).Debug().Msg("Initialization of traversers was successful")
 }
 
-func (t *AstTraverser) Traverse(ctx context.Context) (chan map[string][]string, chan map[string][]string) {
-	restAPIsMapChan := make(chan map[string][]string)
-	zapiCommandsMapChan := make(chan map[string][]string)
+func (t *AstTraverser) Traverse(ctx context.Context) (chan map[string]Finding, chan map[string]Finding) {
+	restAPIsMapChan := make(chan map[string]Finding)
+	zapiCommandsMapChan := make(chan map[string]Finding)
 
 	if t.rest {
 		t.restTraverser.Traverse(ctx, restAPIsMapChan)
@@ -1,58 +1,397 @@
 package bfs
 
 import (
-	"github.com/theshashankpal/api-collector/loader"
+	"context"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
 	"sync"
+
+	"golang.org/x/tools/go/packages"
+
+	. "github.com/theshashankpal/api-collector/callgraph"
+	"github.com/theshashankpal/api-collector/loader"
+	. "github.com/theshashankpal/api-collector/logger"
+	"github.com/theshashankpal/api-collector/overlay"
+	. "github.com/theshashankpal/api-collector/traverser"
+	. "github.com/theshashankpal/api-collector/traverser/ast-traverser/dfs/recurser"
 )
 
+var bfsF = LogFields{Key: "layer", Value: "bfs-traverser"}
+
+// Target is what the BfsTraverser looks for, and where it starts from.
+type Target int
+
+const (
+	RESTTarget Target = iota
+	ZAPITarget
+)
+
+func (t Target) String() string {
+	switch t {
+	case RESTTarget:
+		return "REST"
+	case ZAPITarget:
+		return "ZAPI"
+	default:
+		return "Unknown"
+	}
+}
+
+// children are what the walk goes down to from a function. The implementations of an interface method are
+// reached through the same call as the method, hence at the same distance from the root, unlike its callees.
+type children struct {
+	// api is the scraped API, if the function issues one, it then has no children.
+	api             []string
+	callees         []Function
+	implementations []Function
+}
+
+// BfsTraverser walks down level by level from the functions the roots select, so that every API
+// is reached from every root over the fewest calls. Results are keyed by the functionID of the operation, same as
+// the DfsTraverser, the Finding holding the distance and the chain of every root reaching it.
 type BfsTraverser struct {
-	pack []*loader.Package
-	wg   *sync.WaitGroup
-}
-
-//func bfsTraverseOutgoingCalls(startFileName string, startLine, startCharacter int, startFunctionName string) error {
-//	queue := []struct {
-//		fileName     string
-//		line         int
-//		character    int
-//		functionName string
-//	}{{startFileName, startLine, startCharacter, startFunctionName}}
-//
-//	visited := make(map[string]bool)
-//
-//	for len(queue) > 0 {
-//		current := queue[0]
-//		queue = queue[1:]
-//
-//		// Construct a unique identifier for each function to avoid revisiting.
-//		funcID := fmt.Sprintf("%s:%s", current.fileName, current.functionName)
-//		if visited[funcID] {
-//			continue
-//		}
-//		visited[funcID] = true
-//
-//		// Process the current function, e.g., print its name.
-//		fmt.Println("Visiting function:", current.functionName)
-//
-//		outgoingCalls, err := findCallees(current.fileName, current.line, current.character)
-//		if err != nil {
-//			fmt.Println("Error finding callees for function:", current.functionName, "-", err)
-//			continue
-//		}
-//
-//		for _, call := range outgoingCalls {
-//			if !visited[fmt.Sprintf("%s:%s", call.To.Uri, call.To.Name)] && strings.Contains(call.To.Uri, "github.com/netapp/trident") {
-//				// Assuming you can extract line and character from call.To.Uri or another way.
-//				// Add the callee to the queue.
-//				queue = append(queue, struct {
-//					fileName     string
-//					line         int
-//					character    int
-//					functionName string
-//				}{call.To.Uri, 0, 0, call.To.Name}) // Update line and character accordingly.
-//			}
-//		}
-//	}
-//
-//	return nil
-//}
+	callGraph CallGraph
+	workDir   string
+	overlay   overlay.Overlay
+	target    Target
+	// maxDepth is the distance past which the walk stops, zero doesn't stop it.
+	maxDepth int
+
+	// roots select the functions the walk starts from, scope the packages it goes down to.
+	roots Roots
+	scope Scope
+	// operations are the packages of the functions issuing the ONTAP calls, pkgPaths the package of every file.
+	operations Operations
+	pkgPaths   map[string]string
+
+	// isOperation tells whether the function declared in the file issues an ONTAP call, scrape returns it.
+	isOperation func(filePath string, functionName string) bool
+	scrape      func(ctx context.Context, file *ast.File, functionName string) []string
+
+	fset *token.FileSet
+	pkgs []*loader.Package
+	// Don't need a mutex for fileMap, as it is read-only
+	fileMap map[string]*ast.File
+
+	// Roots share most of their callees, hence every function is looked up only once.
+	children   map[string]children
+	childrenMU *sync.Mutex
+
+	findings   map[string]Finding
+	findingsMU *sync.Mutex
+
+	initialized bool
+}
+
+// NewBfsTraverser loads the packages of workDir with the overlay on top of them, the same one the callGraph analyzes.
+func NewBfsTraverser(callGraph CallGraph, workDir string, overlay overlay.Overlay, target Target, roots Roots,
+	scope Scope, operations Operations, maxDepth int) *BfsTraverser {
+	b := &BfsTraverser{
+		callGraph:  callGraph,
+		workDir:    workDir,
+		overlay:    overlay,
+		target:     target,
+		roots:      roots,
+		scope:      scope,
+		operations: operations,
+		maxDepth:   maxDepth,
+		children:   make(map[string]children),
+		childrenMU: new(sync.Mutex),
+		findings:   make(map[string]Finding),
+		findingsMU: new(sync.Mutex),
+	}
+
+	switch target {
+	case ZAPITarget:
+		b.isOperation = func(filePath string, functionName string) bool {
+			return b.operations.IsZAPI(b.pkgPaths[filePath], filePath, functionName)
+		}
+		b.scrape = ScrapeZAPICommand
+	default:
+		b.isOperation = func(filePath string, functionName string) bool {
+			return b.operations.IsREST(b.pkgPaths[filePath], filePath)
+		}
+		b.scrape = func(ctx context.Context, file *ast.File, functionName string) []string {
+			return ScrapeRESTAPI(ctx, b.fset, file, functionName)
+		}
+	}
+	return b
+}
+
+func (b *BfsTraverser) Initialize(ctx context.Context, done chan bool) {
+	Log(ctx, bfsF).Debug().
+		Stringer("target", b.target).
+		Msgf("Loading packages at work directory : %s", b.workDir)
+
+	pkgs, err := loader.LoadRootsWithConfig(&packages.Config{Overlay: b.overlay}, b.workDir)
+	if err != nil {
+		Log(ctx, bfsF).Panic().Stack().
+			Stringer("target", b.target).
+			Msgf("Error : %s", err)
+	}
+
+	fileMap := make(map[string]*ast.File)
+	for _, pkg := range pkgs {
+		// Adding only the package which contains api calls, or the roots
+		if b.scope.Contains(pkg.PkgPath) || b.roots.Holds(b.workDir, pkg) {
+			pkg.NeedSyntax()
+			for _, file := range pkg.Syntax {
+				fileMap[pkg.Fset.File(file.Package).Name()] = file
+			}
+		}
+	}
+	b.SetPackages(pkgs)
+	b.SetFileMap(fileMap)
+	Log(ctx, bfsF).Debug().
+		Stringer("target", b.target).
+		Msgf("AST syntax at `%s` loaded successfully", b.scope)
+
+	done <- true
+}
+
+func (b *BfsTraverser) Traverse(ctx context.Context, mapChan chan map[string]Finding) {
+	if !b.initialized {
+		Log(ctx, bfsF).Panic().Stack().Msg("BfsTraverser not initialized")
+	}
+
+	go func() {
+		wg := new(sync.WaitGroup)
+		for _, root := range b.rootFunctions(ctx) {
+			wg.Add(1)
+			go func(root Function) {
+				defer wg.Done()
+				b.walk(ctx, root)
+			}(root)
+		}
+		wg.Wait()
+		mapChan <- b.results()
+	}()
+}
+
+// rootFunctions returns the functions the roots select.
+func (b *BfsTraverser) rootFunctions(ctx context.Context) []Function {
+	roots, unmatched := b.roots.Select(b.workDir, b.pkgs)
+	if len(unmatched) > 0 {
+		Log(ctx, bfsF).Error().Strs("roots", unmatched).Msg("Roots matching no function, mistyped or outside the work directory")
+	}
+	return roots
+}
+
+// walk goes down from the root one level at a time, a level holding the functions at the same call distance
+// from it. A function is only visited at the first level reaching it, the shortest distance.
+func (b *BfsTraverser) walk(ctx context.Context, root Function) {
+	Log(ctx, bfsF).Debug().Str("functionID", root.ID()).Msg("Walking down from root")
+
+	// parents maps every function visited to the one it was reached from, the root to itself.
+	parents := map[string]Function{root.ID(): root}
+	level := []Function{root}
+	for distance := 0; len(level) > 0; distance++ {
+		var callees []edge
+		// The implementations found while expanding a level belong to it, and are expanded in turn.
+		for len(level) > 0 {
+			var implementations []edge
+			for i, found := range b.expand(ctx, level) {
+				if len(found.api) > 0 {
+					b.record(level[i], found.api, chainOf(parents, level[i]), distance)
+					continue
+				}
+				for _, implementation := range found.implementations {
+					implementations = append(implementations, edge{level[i], implementation})
+				}
+				for _, callee := range found.callees {
+					callees = append(callees, edge{level[i], callee})
+				}
+			}
+			level = unvisited(parents, implementations)
+		}
+
+		if b.maxDepth > 0 && distance >= b.maxDepth {
+			return
+		}
+		level = unvisited(parents, callees)
+	}
+}
+
+// edge leads from a function to one of its children.
+type edge struct {
+	from Function
+	to   Function
+}
+
+// unvisited returns the functions the edges lead to which aren't visited yet, once each, marking them visited.
+func unvisited(parents map[string]Function, edges []edge) []Function {
+	var result []Function
+	for _, e := range edges {
+		if _, ok := parents[e.to.ID()]; !ok {
+			parents[e.to.ID()] = e.from
+			result = append(result, e.to)
+		}
+	}
+	return result
+}
+
+// chainOf returns the functionIDs of the functions from the root down to f, following the parents back up.
+func chainOf(parents map[string]Function, f Function) []string {
+	chain := []string{f.ID()}
+	for parent := parents[f.ID()]; parent.ID() != f.ID(); parent = parents[f.ID()] {
+		f = parent
+		chain = append(chain, f.ID())
+	}
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return chain
+}
+
+// expand looks up the children of every function of the level at once.
+func (b *BfsTraverser) expand(ctx context.Context, level []Function) []children {
+	result := make([]children, len(level))
+	wg := new(sync.WaitGroup)
+	for i, f := range level {
+		wg.Add(1)
+		go func(i int, f Function) {
+			defer wg.Done()
+			result[i] = b.childrenOf(ctx, f)
+		}(i, f)
+	}
+	wg.Wait()
+	return result
+}
+
+func (b *BfsTraverser) childrenOf(ctx context.Context, f Function) children {
+	b.childrenMU.Lock()
+	cached, ok := b.children[f.ID()]
+	b.childrenMU.Unlock()
+	if ok {
+		return cached
+	}
+
+	Log(ctx, bfsF).Trace().
+		Str("functionName", f.FunctionName).
+		Str("functionID", f.ID()).
+		Msg("Visiting function")
+
+	var result children
+	if file, ok := b.fileMap[f.FilePath]; ok && b.isOperation(f.FilePath, f.FunctionName) {
+		if api := b.scrape(ctx, file, f.FunctionName); len(api) > 0 && api[0] != "" {
+			result.api = api
+		}
+	}
+
+	if result.api == nil {
+		result.callees, result.implementations = b.callsOf(ctx, f)
+	}
+
+	b.childrenMU.Lock()
+	b.children[f.ID()] = result
+	b.childrenMU.Unlock()
+	return result
+}
+
+// callsOf returns the callees of the function within the ONTAP api packages or, if it has none and is
+// an interface method, its implementations.
+func (b *BfsTraverser) callsOf(ctx context.Context, f Function) (callees, implementations []Function) {
+	outgoingCallsChan := b.callGraph.OutgoingCalls(ctx, f.FilePath, f.Line, f.Character)
+	outgoingCalls := <-outgoingCallsChan
+	if outgoingCalls.Error != nil {
+		Log(ctx, bfsF).Error().
+			Int("ErrorCode", outgoingCalls.Error.Code).
+			Str("Error", outgoingCalls.Error.Message).
+			Str("FilePath", f.FilePath).
+			Str("FunctionName", f.FunctionName).
+			Int("Character", f.Character).
+			Int("Line", f.Line).
+			Msg("Error getting outgoing calls")
+		return nil, nil
+	}
+
+	for _, call := range outgoingCalls.Result {
+		// Don't want to explore callee of other packages
+		if !b.scope.ContainsDetail(call.To.Detail) {
+			continue
+		}
+		start := call.To.Range.Start
+		callees = append(callees, Function{FilePath: strings.ReplaceAll(call.To.Uri, "file://", ""), Line: start.Line,
+			Character: start.Character, FunctionName: call.To.Name})
+	}
+
+	// outGoingCalls can be of length 0, indicating that we might have encountered an interface.
+	if len(outgoingCalls.Result) > 0 || !b.isInterfaceMethod(f) {
+		return callees, nil
+	}
+
+	implementationsChan := b.callGraph.Implementations(ctx, f.FilePath, f.Line, f.Character)
+	implementation := <-implementationsChan
+	if implementation.Error != nil {
+		Log(ctx, bfsF).Error().
+			Int("ErrorCode", implementation.Error.Code).
+			Str("Error", implementation.Error.Message).
+			Str("FilePath", f.FilePath).
+			Str("FunctionName", f.FunctionName).
+			Int("Character", f.Character).
+			Int("Line", f.Line).
+			Msg("Error getting implementation")
+		return nil, nil
+	}
+	for _, impl := range implementation.Result {
+		if strings.Contains(impl.Uri, "mocks") {
+			continue
+		}
+		implementations = append(implementations, Function{FilePath: strings.ReplaceAll(impl.Uri, "file://", ""),
+			Line: impl.Range.Start.Line, Character: impl.Range.Start.Character, FunctionName: f.FunctionName})
+	}
+	return nil, implementations
+}
+
+// isInterfaceMethod tells whether the function is a method of an interface type.
+// Functions of files not in fileMap aren't.
+func (b *BfsTraverser) isInterfaceMethod(f Function) bool {
+	file, ok := b.fileMap[f.FilePath]
+	return ok && IsInterfaceMethod(b.fset, file, f.FunctionName, f.Line)
+}
+
+// record keeps the API of the operation, along with the shortest chain the root reaches it through.
+// Each root reaches the operation once, at its first level holding it.
+func (b *BfsTraverser) record(operation Function, api []string, chain []string, distance int) {
+	b.findingsMU.Lock()
+	defer b.findingsMU.Unlock()
+
+	found, ok := b.findings[operation.ID()]
+	if !ok {
+		found = Finding{API: api, Distances: make(map[string]int)}
+	}
+	found.Chains = append(found.Chains, chain)
+	found.Roots = append(found.Roots, chain[0])
+	found.Distances[chain[0]] = distance
+	b.findings[operation.ID()] = found
+}
+
+// results returns the findings, their roots and chains sorted by root.
+func (b *BfsTraverser) results() map[string]Finding {
+	b.findingsMU.Lock()
+	defer b.findingsMU.Unlock()
+
+	for _, found := range b.findings {
+		sort.Strings(found.Roots)
+		sort.Slice(found.Chains, func(i, j int) bool {
+			return found.Chains[i][0] < found.Chains[j][0]
+		})
+	}
+	return b.findings
+}
+
+func (b *BfsTraverser) SetFileMap(fileMap map[string]*ast.File) {
+	b.fileMap = fileMap
+}
+
+// SetPackages sets the packages, the walk then being ready. The loader shares its file set among all packages.
+func (b *BfsTraverser) SetPackages(pkgs []*loader.Package) {
+	b.pkgs = pkgs
+	b.pkgPaths = PackagePaths(pkgs)
+	if len(pkgs) > 0 {
+		b.fset = pkgs[0].Fset
+	}
+	b.initialized = true
+}
@@ -0,0 +1,168 @@
+package bfs_test
+
+import (
+	"context"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/loader"
+	"github.com/theshashankpal/api-collector/traverser"
+	"github.com/theshashankpal/api-collector/traverser/ast-traverser/bfs"
+)
+
+const (
+	trident  = "github.com/netapp/trident"
+	ontapAPI = trident + "/storage_drivers/ontap/api"
+)
+
+var operations = traverser.DefaultOperations(trident)
+
+var _ = Describe("BfsTraverser", func() {
+	var (
+		ctx       = context.Background()
+		workDir   string
+		pkgs      []*loader.Package
+		fileMap   map[string]*ast.File
+		server    *lsptest.Server
+		callGraph *lsp.AbstractionLSP
+
+		root, iface, client lsptest.Function
+	)
+
+	// function returns the scripted function declared as name in the file ending with fileSuffix.
+	// Interface methods are looked up as well.
+	function := func(fileSuffix, name string) lsptest.Function {
+		for filePath, file := range fileMap {
+			if !strings.HasSuffix(filePath, fileSuffix) {
+				continue
+			}
+
+			var ident *ast.Ident
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch typeNode := node.(type) {
+				case *ast.FuncDecl:
+					if typeNode.Name.Name == name {
+						ident = typeNode.Name
+					}
+				case *ast.Field:
+					if len(typeNode.Names) > 0 && typeNode.Names[0].Name == name {
+						ident = typeNode.Names[0]
+					}
+				}
+				return ident == nil
+			})
+			Expect(ident).ToNot(BeNil())
+
+			position := pkgs[0].Fset.Position(ident.Pos())
+			detail := fmt.Sprintf("%s • %s", ontapAPI, filepath.Base(filePath))
+			return lsptest.NewFunction(filePath, position.Line-1, position.Column-1, name, detail)
+		}
+		Fail(fmt.Sprintf("no file ending with %s", fileSuffix))
+		return lsptest.Function{}
+	}
+
+	// functionID identifies the function the way the traverser keys its results.
+	functionID := func(f lsptest.Function) string {
+		start := f.Item.SelectionRange.Start
+		return traverser.Function{FilePath: strings.TrimPrefix(f.Item.Uri, "file://"), Line: start.Line,
+			Character: start.Character, FunctionName: f.Item.Name}.ID()
+	}
+
+	traverse := func(target bfs.Target, maxDepth int) map[string]traverser.Finding {
+		roots := traverser.DefaultRESTRoots
+		if target == bfs.ZAPITarget {
+			roots = traverser.DefaultZAPIRoots
+		}
+		b := bfs.NewBfsTraverser(callGraph, workDir, nil, target, roots, traverser.APIScope(operations), operations, maxDepth)
+		b.SetPackages(pkgs)
+		b.SetFileMap(fileMap)
+		mapChan := make(chan map[string]traverser.Finding)
+		b.Traverse(ctx, mapChan)
+		return <-mapChan
+	}
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = filepath.Abs("../dfs/recurser/testdata/trident")
+		Expect(err).ToNot(HaveOccurred())
+
+		pkgs, err = loader.LoadRootsWithConfig(&packages.Config{Dir: workDir}, "./...")
+		Expect(err).ToNot(HaveOccurred())
+
+		fileMap = make(map[string]*ast.File)
+		for _, pkg := range pkgs {
+			if strings.Contains(pkg.PkgPath, ontapAPI) {
+				pkg.NeedSyntax()
+				for _, file := range pkg.Syntax {
+					fileMap[pkg.Fset.File(file.Package).Name()] = file
+				}
+			}
+		}
+
+		server = lsptest.NewServer()
+		callGraph = lsp.NewAbstractionLSP(ctx, server.Conn(), workDir, "trident", lsp.Config{})
+		Expect(callGraph.Initialize(ctx)).To(Succeed())
+
+		root = function("api/ontap_rest.go", "VolumeCreate")
+		iface = function("api/volumes.go", "VolumeCreate")
+		client = function("storage/volume_client.go", "VolumeCreate")
+		iface.Implementations = []requests.Location{client.Location()}
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should give the shortest call distance and chain, the implementations being as far as the interface method", func() {
+		// The ZAPI root stands for a function between the REST root and the interface.
+		helper := function("api/ontap_zapi.go", "VolumeCreate")
+		root.Calls = []requests.CallHierarchyItem{helper.Item, iface.Item}
+		helper.Calls = []requests.CallHierarchyItem{iface.Item}
+		server.Script(root, helper, iface, client)
+
+		restAPIs := traverse(bfs.RESTTarget, 0)
+
+		Expect(restAPIs).To(Equal(map[string]traverser.Finding{functionID(client): {
+			API:       []string{"POST", "/storage/volumes"},
+			Chains:    [][]string{{functionID(root), functionID(iface), functionID(client)}},
+			Roots:     []string{functionID(root)},
+			Distances: map[string]int{functionID(root): 1},
+		}}))
+	})
+
+	It("should stop the walk at the depth cap", func() {
+		helper := function("api/ontap_zapi.go", "VolumeCreate")
+		root.Calls = []requests.CallHierarchyItem{helper.Item}
+		helper.Calls = []requests.CallHierarchyItem{iface.Item}
+		server.Script(root, helper, iface, client)
+
+		Expect(traverse(bfs.RESTTarget, 1)).To(BeEmpty())
+		Expect(traverse(bfs.RESTTarget, 2)).To(HaveLen(1))
+	})
+
+	It("should find ZAPI commands", func() {
+		root = function("api/ontap_zapi.go", "VolumeCreate")
+		constructor := function("azgo/api-volume-create.go", "NewVolumeCreateRequest")
+		executeUsing := function("azgo/api-volume-create.go", "ExecuteUsing")
+		root.Calls = []requests.CallHierarchyItem{constructor.Item, executeUsing.Item}
+		server.Script(root, constructor, executeUsing)
+
+		zapiCommands := traverse(bfs.ZAPITarget, 0)
+
+		Expect(zapiCommands).To(Equal(map[string]traverser.Finding{functionID(executeUsing): {
+			API:       []string{"volume-create"},
+			Chains:    [][]string{{functionID(root), functionID(executeUsing)}},
+			Roots:     []string{functionID(root)},
+			Distances: map[string]int{functionID(root): 1},
+		}}))
+	})
+})
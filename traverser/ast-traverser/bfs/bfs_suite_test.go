@@ -0,0 +1,13 @@
+package bfs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestBfs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "BFS Traverser Suite")
+}
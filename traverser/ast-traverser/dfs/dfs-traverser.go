@@ -5,11 +5,12 @@ import (
 	. "github.com/theshashankpal/api-collector/callgraph"
 	"github.com/theshashankpal/api-collector/loader"
 	. "github.com/theshashankpal/api-collector/logger"
+	"github.com/theshashankpal/api-collector/overlay"
+	. "github.com/theshashankpal/api-collector/traverser"
 	. "github.com/theshashankpal/api-collector/traverser/ast-traverser/dfs/recurser"
 	"go/ast"
 	"go/token"
-	"strings"
-	"sync"
+	"golang.org/x/tools/go/packages"
 )
 
 type RecurserType int
@@ -17,6 +18,8 @@ type RecurserType int
 const (
 	RESTRecurserType RecurserType = iota
 	ZAPIRecurserType
+	RESTCallersRecurserType
+	ZAPICallersRecurserType
 )
 
 func (r RecurserType) String() string {
@@ -25,6 +28,10 @@ func (r RecurserType) String() string {
 		return "RESTRecurser"
 	case ZAPIRecurserType:
 		return "ZAPIRecurser"
+	case RESTCallersRecurserType:
+		return "RESTCallersRecurser"
+	case ZAPICallersRecurserType:
+		return "ZAPICallersRecurser"
 	default:
 		return "Unknown"
 	}
@@ -33,7 +40,7 @@ func (r RecurserType) String() string {
 var dfsF = LogFields{Key: "layer", Value: "dfs-traverser"}
 
 type recurser interface {
-	Traverse(ctx context.Context, restAPIsMapChan chan map[string][]string)
+	Traverse(ctx context.Context, restAPIsMapChan chan map[string]Finding)
 	SetFileSet(fset *token.FileSet)
 	SetFileMap(fileMap map[string]*ast.File)
 	SetPackages(pkgs []*loader.Package)
@@ -42,22 +49,51 @@ type recurser interface {
 type DfsTraverser struct {
 	recurser
 	workDir      string
+	overlay      overlay.Overlay
+	roots        Roots
+	scope        Scope
 	initialized  bool
 	recurserType RecurserType
 }
 
-func NewDfsTraverser(callGraph CallGraph, callGraphMu *sync.Mutex, workDir string, recurserType RecurserType) *DfsTraverser {
+// NewDfsTraverser loads the packages of workDir with the overlay on top of them, the same one the callGraph analyzes.
+// Walking down starts from the functions the roots select, walking up ignores them. Either way, the walk stays
+// within the scope, the operations telling the functions which issue the ONTAP calls.
+func NewDfsTraverser(callGraph CallGraph, workDir string, overlay overlay.Overlay, recurserType RecurserType,
+	roots Roots, scope Scope, operations Operations) *DfsTraverser {
 	switch recurserType {
 	case RESTRecurserType:
 		return &DfsTraverser{
 			workDir:      workDir,
-			recurser:     NewRESTRecurser(callGraph, callGraphMu),
+			overlay:      overlay,
+			scope:        scope,
+			roots:        roots,
+			recurser:     NewRESTRecurser(callGraph, workDir, roots, scope, operations),
 			recurserType: recurserType,
 		}
 	case ZAPIRecurserType:
 		return &DfsTraverser{
 			workDir:      workDir,
-			recurser:     NewZAPIRecurser(callGraph, callGraphMu),
+			overlay:      overlay,
+			scope:        scope,
+			roots:        roots,
+			recurser:     NewZAPIRecurser(callGraph, workDir, roots, scope, operations),
+			recurserType: recurserType,
+		}
+	case RESTCallersRecurserType:
+		return &DfsTraverser{
+			workDir:      workDir,
+			overlay:      overlay,
+			scope:        scope,
+			recurser:     NewRESTCallersRecurser(callGraph, scope, operations),
+			recurserType: recurserType,
+		}
+	case ZAPICallersRecurserType:
+		return &DfsTraverser{
+			workDir:      workDir,
+			overlay:      overlay,
+			scope:        scope,
+			recurser:     NewZAPICallersRecurser(callGraph, scope, operations),
 			recurserType: recurserType,
 		}
 	default:
@@ -71,7 +107,7 @@ func (d *DfsTraverser) Initialize(ctx context.Context, done chan bool) {
 		Stringer("recurserType", d.recurserType).
 		Msgf("Loading packages at work direcrory : %s", d.workDir)
 
-	pack, err := loader.LoadRoots(d.workDir)
+	pack, err := loader.LoadRootsWithConfig(&packages.Config{Overlay: d.overlay}, d.workDir)
 	if err != nil {
 		Log(ctx, dfsF).Panic().Stack().
 			Stringer("recurserType", d.recurserType).
@@ -81,11 +117,11 @@ func (d *DfsTraverser) Initialize(ctx context.Context, done chan bool) {
 
 	Log(ctx, dfsF).Debug().
 		Stringer("recurserType", d.recurserType).
-		Msgf("Loading AST syntax for packages at `github.com/netapp/trident/storage_drivers/ontap/api`")
+		Msgf("Loading AST syntax for packages at `%s`", d.scope)
 	fileMap := make(map[string]*ast.File)
 	for _, pkg := range pack {
-		// Adding only the package which contains api calls
-		if strings.Contains(pkg.PkgPath, "github.com/netapp/trident/storage_drivers/ontap/api") {
+		// Adding only the package which contains api calls, or the roots
+		if d.scope.Contains(pkg.PkgPath) || d.roots.Holds(d.workDir, pkg) {
 			pkg.NeedSyntax()
 			for _, file := range pkg.Syntax {
 				fileMap[pkg.Fset.File(file.Package).Name()] = file
@@ -95,13 +131,13 @@ func (d *DfsTraverser) Initialize(ctx context.Context, done chan bool) {
 	d.SetFileMap(fileMap)
 	Log(ctx, dfsF).Debug().
 		Stringer("recurserType", d.recurserType).
-		Msgf("AST syntax at `github.com/netapp/trident/storage_drivers/ontap/api` loaded successfully")
+		Msgf("AST syntax at `%s` loaded successfully", d.scope)
 
 	d.initialized = true
 	done <- true
 }
 
-func (d *DfsTraverser) Traverse(ctx context.Context, mapChan chan map[string][]string) {
+func (d *DfsTraverser) Traverse(ctx context.Context, mapChan chan map[string]Finding) {
 	if d.initialized {
 		d.recurser.Traverse(context.Background(), mapChan)
 	} else {
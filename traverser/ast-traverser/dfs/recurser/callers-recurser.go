@@ -0,0 +1,266 @@
+package recurser
+
+import (
+	"context"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/theshashankpal/api-collector/callgraph"
+	"github.com/theshashankpal/api-collector/loader"
+	. "github.com/theshashankpal/api-collector/logger"
+	. "github.com/theshashankpal/api-collector/traverser"
+)
+
+var cr = LogFields{Key: "layer", Value: "callers-dfs-recurser"}
+
+// parents are what the walk goes up to from a function: its callers, and the interface methods
+// it implements, as calls made through an interface are incoming calls of the interface method.
+type parents struct {
+	callers    []Function
+	interfaces []Function
+}
+
+// CallersRecurser walks up from the functions issuing the ONTAP calls, REST client operations or azgo
+// ExecuteUsing methods, to every function within the scope calling them, directly or not.
+// Results are keyed by the functionID of the operation, same as RESTRecurser and ZAPIRecurser,
+// the Finding holding the functionIDs of all its callers.
+type CallersRecurser struct {
+	fset *token.FileSet
+	pkgs []*loader.Package
+
+	// Callgraph is shared between rest and zapi recurser, it is safe for concurrent use
+	callGraph callgraph.CallGraph
+
+	// scope is the packages the walk goes up to, the operations being looked for in them.
+	scope      Scope
+	operations Operations
+
+	// isOperation tells whether the function declared in the file of the package issues an ONTAP call,
+	// scrape returns it.
+	isOperation func(pkgPath, filePath string, funcDecl *ast.FuncDecl) bool
+	scrape      func(ctx context.Context, file *ast.File, functionName string) []string
+
+	// Operations share most of their callers, hence every function is looked up only once.
+	parents   map[string]parents
+	parentsMU *sync.Mutex
+
+	apis   map[string]Finding
+	apisMU *sync.Mutex
+
+	// Don't need a mutex for fileMap, as it is read-only
+	fileMap map[string]*ast.File
+	wg      *sync.WaitGroup
+}
+
+func newCallersRecurser(callGraph callgraph.CallGraph, scope Scope, operations Operations) *CallersRecurser {
+	return &CallersRecurser{
+		callGraph:  callGraph,
+		scope:      scope,
+		operations: operations,
+		parents:    make(map[string]parents),
+		parentsMU:  new(sync.Mutex),
+		apis:       make(map[string]Finding),
+		apisMU:     new(sync.Mutex),
+		wg:         new(sync.WaitGroup),
+	}
+}
+
+// NewRESTCallersRecurser walks up from the REST client operations of the REST operation packages.
+func NewRESTCallersRecurser(callGraph callgraph.CallGraph, scope Scope, operations Operations) *CallersRecurser {
+	c := newCallersRecurser(callGraph, scope, operations)
+	c.isOperation = func(pkgPath, filePath string, funcDecl *ast.FuncDecl) bool {
+		return funcDecl.Recv != nil && c.operations.IsREST(pkgPath, filePath)
+	}
+	c.scrape = func(ctx context.Context, file *ast.File, functionName string) []string {
+		return ScrapeRESTAPI(ctx, c.fset, file, functionName)
+	}
+	return c
+}
+
+// NewZAPICallersRecurser walks up from the ExecuteUsing methods of the requests of the ZAPI operation packages.
+func NewZAPICallersRecurser(callGraph callgraph.CallGraph, scope Scope, operations Operations) *CallersRecurser {
+	c := newCallersRecurser(callGraph, scope, operations)
+	c.isOperation = func(pkgPath, filePath string, funcDecl *ast.FuncDecl) bool {
+		return funcDecl.Recv != nil && c.operations.IsZAPI(pkgPath, filePath, funcDecl.Name.Name)
+	}
+	c.scrape = ScrapeZAPICommand
+	return c
+}
+
+func (c *CallersRecurser) Traverse(ctx context.Context, apisMapChan chan map[string]Finding) {
+	go func() {
+		// The loader shares its file set among all packages, it is set before any walk reads it.
+		if len(c.pkgs) > 0 {
+			c.fset = c.pkgs[0].Fset
+		}
+
+		for _, pkg := range c.pkgs {
+			if !c.scope.Contains(pkg.PkgPath) {
+				continue
+			}
+			for _, file := range pkg.Syntax {
+				filePath := pkg.Fset.File(file.Package).Name()
+				for _, decl := range file.Decls {
+					funcDecl, ok := decl.(*ast.FuncDecl)
+					if !ok || !c.isOperation(pkg.PkgPath, filePath, funcDecl) {
+						continue
+					}
+
+					api := c.scrape(ctx, file, funcDecl.Name.Name)
+					if len(api) == 0 || api[0] == "" {
+						continue
+					}
+
+					//Indexing starts from 1, hence minus 1.
+					funcPos := pkg.Fset.Position(funcDecl.Name.Pos())
+					operation := Function{FilePath: funcPos.Filename, Line: funcPos.Line - 1, Character: funcPos.Column - 1,
+						FunctionName: funcDecl.Name.Name}
+					c.wg.Add(1)
+					go c.walkUp(ctx, operation, api)
+				}
+			}
+		}
+		c.wg.Wait()
+		apisMapChan <- c.apis
+	}()
+}
+
+// walkUp collects every function reaching the operation, and records them along with its API.
+func (c *CallersRecurser) walkUp(ctx context.Context, operation Function, api []string) {
+	defer c.wg.Done()
+
+	Log(ctx, cr).Debug().Str("functionID", operation.ID()).Strs("api", api).Msg("Walking up from operation")
+
+	visited := map[string]struct{}{operation.ID(): {}}
+	callers := make([]string, 0)
+	stack := []Function{operation}
+	for len(stack) > 0 {
+		current := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+
+		found := c.parentsOf(ctx, current)
+		for _, iface := range found.interfaces {
+			if _, ok := visited[iface.ID()]; !ok {
+				visited[iface.ID()] = struct{}{}
+				stack = append(stack, iface)
+			}
+		}
+		for _, caller := range found.callers {
+			if _, ok := visited[caller.ID()]; !ok {
+				visited[caller.ID()] = struct{}{}
+				callers = append(callers, caller.ID())
+				stack = append(stack, caller)
+			}
+		}
+	}
+	sort.Strings(callers)
+
+	c.apisMU.Lock()
+	c.apis[operation.ID()] = Finding{API: api, Callers: callers}
+	c.apisMU.Unlock()
+}
+
+func (c *CallersRecurser) parentsOf(ctx context.Context, f Function) parents {
+	c.parentsMU.Lock()
+	cached, ok := c.parents[f.ID()]
+	c.parentsMU.Unlock()
+	if ok {
+		return cached
+	}
+
+	Log(ctx, cr).Trace().
+		Str("functionName", f.FunctionName).
+		Str("functionID", f.ID()).
+		Msg("Visiting function")
+
+	var result parents
+
+	incomingCallsChan := c.callGraph.IncomingCalls(ctx, f.FilePath, f.Line, f.Character)
+	incomingCalls := <-incomingCallsChan
+	if incomingCalls.Error != nil {
+		Log(ctx, cr).Error().
+			Int("ErrorCode", incomingCalls.Error.Code).
+			Str("Error", incomingCalls.Error.Message).
+			Str("FilePath", f.FilePath).
+			Str("FunctionName", f.FunctionName).
+			Int("Character", f.Character).
+			Int("Line", f.Line).
+			Msg("Error getting incoming calls")
+	} else {
+		for _, call := range incomingCalls.Result {
+			// Only callers within the scope are of interest, leaving out mocks and tests.
+			filePath := strings.ReplaceAll(call.From.Uri, "file://", "")
+			if !c.scope.ContainsDetail(call.From.Detail) ||
+				strings.Contains(filePath, "mocks") || strings.HasSuffix(filePath, "_test.go") {
+				continue
+			}
+			start := call.From.Range.Start
+			result.callers = append(result.callers, Function{FilePath: filePath, Line: start.Line, Character: start.Character,
+				FunctionName: call.From.Name})
+		}
+	}
+
+	// A method can be called through any interface it implements.
+	if method, _ := c.declaredAt(f); method {
+		implementationsChan := c.callGraph.Implementations(ctx, f.FilePath, f.Line, f.Character)
+		implementation := <-implementationsChan
+		if implementation.Error != nil {
+			Log(ctx, cr).Error().
+				Int("ErrorCode", implementation.Error.Code).
+				Str("Error", implementation.Error.Message).
+				Str("FilePath", f.FilePath).
+				Str("FunctionName", f.FunctionName).
+				Int("Character", f.Character).
+				Int("Line", f.Line).
+				Msg("Error getting implementation")
+		} else {
+			for _, impl := range implementation.Result {
+				if strings.Contains(impl.Uri, "mocks") {
+					continue
+				}
+				iface := Function{FilePath: strings.ReplaceAll(impl.Uri, "file://", ""), Line: impl.Range.Start.Line,
+					Character: impl.Range.Start.Character, FunctionName: f.FunctionName}
+				if _, interfaceMethod := c.declaredAt(iface); interfaceMethod {
+					result.interfaces = append(result.interfaces, iface)
+				}
+			}
+		}
+	}
+
+	c.parentsMU.Lock()
+	c.parents[f.ID()] = result
+	c.parentsMU.Unlock()
+	return result
+}
+
+// declaredAt tells whether the function is a method with a receiver, or an interface method.
+// Functions of files not in fileMap are neither.
+func (c *CallersRecurser) declaredAt(f Function) (method, interfaceMethod bool) {
+	file, ok := c.fileMap[f.FilePath]
+	if !ok {
+		return false, false
+	}
+
+	for _, decl := range file.Decls {
+		if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv != nil && funcDecl.Name.Name == f.FunctionName &&
+			c.fset.Position(funcDecl.Name.Pos()).Line-1 == f.Line {
+			return true, false
+		}
+	}
+	return false, IsInterfaceMethod(c.fset, file, f.FunctionName, f.Line)
+}
+
+func (c *CallersRecurser) SetFileSet(fset *token.FileSet) {
+	c.fset = fset
+}
+
+func (c *CallersRecurser) SetFileMap(fileMap map[string]*ast.File) {
+	c.fileMap = fileMap
+}
+
+func (c *CallersRecurser) SetPackages(pkgs []*loader.Package) {
+	c.pkgs = pkgs
+}
@@ -0,0 +1,88 @@
+package recurser
+
+import (
+	"sort"
+	"sync"
+
+	. "github.com/theshashankpal/api-collector/traverser"
+)
+
+// calls keeps every call met while walking down. A function is only visited by the first root reaching it,
+// hence the roots reaching an operation through an already visited function are only found once the walk is over.
+type calls struct {
+	roots   []string
+	callees map[string][]string
+	mutex   *sync.Mutex
+}
+
+func newCalls() *calls {
+	return &calls{
+		callees: make(map[string][]string),
+		mutex:   new(sync.Mutex),
+	}
+}
+
+// add keeps the last call of the chain, or its root if it holds nothing else.
+func (c *calls) add(chain []string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if len(chain) == 1 {
+		c.roots = append(c.roots, chain[0])
+		return
+	}
+	caller := chain[len(chain)-2]
+	c.callees[caller] = append(c.callees[caller], chain[len(chain)-1])
+}
+
+// attribute sets the roots of every finding, and the chain each of them reaches it through, following the calls
+// kept from each root down. Roots are taken in order and callees level by level, in order too, hence the chain
+// is the shortest one, the same from one run to the next whichever goroutine first reached the finding.
+func (c *calls) attribute(findings map[string]Finding) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	sort.Strings(c.roots)
+	for _, callees := range c.callees {
+		sort.Strings(callees)
+	}
+
+	for i, root := range c.roots {
+		if i > 0 && root == c.roots[i-1] {
+			continue
+		}
+
+		// callers holds the function every reached one was first called from, the root being called from none.
+		callers := map[string]string{root: ""}
+		level := []string{root}
+		for len(level) > 0 {
+			var next []string
+			for _, functionID := range level {
+				if finding, ok := findings[functionID]; ok {
+					finding.Roots = append(finding.Roots, root)
+					finding.Chains = append(finding.Chains, chain(callers, functionID))
+					findings[functionID] = finding
+				}
+				for _, callee := range c.callees[functionID] {
+					if _, ok := callers[callee]; !ok {
+						callers[callee] = functionID
+						next = append(next, callee)
+					}
+				}
+			}
+			level = next
+		}
+	}
+}
+
+// chain returns the functions from the root down to the given one, following back who first called each.
+func chain(callers map[string]string, functionID string) []string {
+	var functionIDs []string
+	for ; functionID != ""; functionID = callers[functionID] {
+		functionIDs = append(functionIDs, functionID)
+	}
+	for i, j := 0, len(functionIDs)-1; i < j; i, j = i+1, j-1 {
+		functionIDs[i], functionIDs[j] = functionIDs[j], functionIDs[i]
+	}
+	return functionIDs
+}
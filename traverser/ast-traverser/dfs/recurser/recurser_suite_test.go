@@ -0,0 +1,13 @@
+package recurser_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestRecurser(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Recurser Suite")
+}
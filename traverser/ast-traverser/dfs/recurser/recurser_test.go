@@ -0,0 +1,237 @@
+package recurser_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/theshashankpal/api-collector/callgraph/lsp"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/lsptest"
+	"github.com/theshashankpal/api-collector/callgraph/lsp/requests"
+	"github.com/theshashankpal/api-collector/loader"
+	"github.com/theshashankpal/api-collector/traverser"
+	"github.com/theshashankpal/api-collector/traverser/ast-traverser/dfs/recurser"
+)
+
+const (
+	trident  = "github.com/netapp/trident"
+	ontapAPI = trident + "/storage_drivers/ontap/api"
+)
+
+var operations = traverser.DefaultOperations(trident)
+
+type testRecurser interface {
+	Traverse(ctx context.Context, mapChan chan map[string]traverser.Finding)
+	SetFileMap(fileMap map[string]*ast.File)
+	SetPackages(pkgs []*loader.Package)
+}
+
+var _ = Describe("Recursers", func() {
+	var (
+		ctx       = context.Background()
+		workDir   string
+		pkgs      []*loader.Package
+		fileMap   map[string]*ast.File
+		server    *lsptest.Server
+		callGraph *lsp.AbstractionLSP
+	)
+
+	// function returns the scripted function declared as name in the file ending with fileSuffix.
+	// Interface methods are looked up as well.
+	function := func(fileSuffix, name string) lsptest.Function {
+		for filePath, file := range fileMap {
+			if !strings.HasSuffix(filePath, fileSuffix) {
+				continue
+			}
+
+			var ident *ast.Ident
+			ast.Inspect(file, func(node ast.Node) bool {
+				switch typeNode := node.(type) {
+				case *ast.FuncDecl:
+					if typeNode.Name.Name == name {
+						ident = typeNode.Name
+					}
+				case *ast.Field:
+					if len(typeNode.Names) > 0 && typeNode.Names[0].Name == name {
+						ident = typeNode.Names[0]
+					}
+				}
+				return ident == nil
+			})
+			Expect(ident).ToNot(BeNil())
+
+			position := pkgs[0].Fset.Position(ident.Pos())
+			detail := fmt.Sprintf("%s • %s", ontapAPI, filepath.Base(filePath))
+			return lsptest.NewFunction(filePath, position.Line-1, position.Column-1, name, detail)
+		}
+		Fail(fmt.Sprintf("no file ending with %s", fileSuffix))
+		return lsptest.Function{}
+	}
+
+	// functionID identifies the function the way the recursers key their results.
+	functionID := func(f lsptest.Function) string {
+		start := f.Item.SelectionRange.Start
+		return traverser.Function{FilePath: strings.TrimPrefix(f.Item.Uri, "file://"), Line: start.Line,
+			Character: start.Character, FunctionName: f.Item.Name}.ID()
+	}
+
+	traverse := func(r testRecurser) map[string]traverser.Finding {
+		r.SetPackages(pkgs)
+		r.SetFileMap(fileMap)
+		mapChan := make(chan map[string]traverser.Finding)
+		r.Traverse(ctx, mapChan)
+		return <-mapChan
+	}
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = filepath.Abs("testdata/trident")
+		Expect(err).ToNot(HaveOccurred())
+
+		pkgs, err = loader.LoadRootsWithConfig(&packages.Config{Dir: workDir}, "./...")
+		Expect(err).ToNot(HaveOccurred())
+
+		fileMap = make(map[string]*ast.File)
+		for _, pkg := range pkgs {
+			if strings.Contains(pkg.PkgPath, ontapAPI) {
+				pkg.NeedSyntax()
+				for _, file := range pkg.Syntax {
+					fileMap[pkg.Fset.File(file.Package).Name()] = file
+				}
+			}
+		}
+
+		server = lsptest.NewServer()
+		callGraph = lsp.NewAbstractionLSP(ctx, server.Conn(), workDir, "trident", lsp.Config{})
+		Expect(callGraph.Initialize(ctx)).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(server.Close()).To(Succeed())
+	})
+
+	It("should find REST APIs through interfaces and their implementations, along with the chain leading there", func() {
+		root := function("api/ontap_rest.go", "VolumeCreate")
+		iface := function("api/volumes.go", "VolumeCreate")
+		client := function("storage/volume_client.go", "VolumeCreate")
+		root.Calls = []requests.CallHierarchyItem{iface.Item}
+		iface.Implementations = []requests.Location{client.Location()}
+		server.Script(root, iface, client)
+
+		restAPIs := traverse(recurser.NewRESTRecurser(callGraph, workDir, traverser.DefaultRESTRoots, traverser.APIScope(operations), operations))
+
+		Expect(restAPIs).To(Equal(map[string]traverser.Finding{functionID(client): {
+			API:    []string{"POST", "/storage/volumes"},
+			Chains: [][]string{{functionID(root), functionID(iface), functionID(client)}},
+			Roots:  []string{functionID(root)},
+		}}))
+	})
+
+	It("should skip the interface methods whose implementations can't be found, REST and ZAPI alike", func() {
+		root := function("api/ontap_rest.go", "VolumeCreate")
+		iface := function("api/volumes.go", "VolumeCreate")
+		root.Calls = []requests.CallHierarchyItem{iface.Item}
+		server.Script(root, iface)
+		server.Handle("textDocument/implementation", func(json.RawMessage) (interface{}, *requests.ResponseError) {
+			return nil, &requests.ResponseError{Code: requests.InternalError, Message: "no function at the position"}
+		})
+
+		Expect(traverse(recurser.NewRESTRecurser(callGraph, workDir, traverser.DefaultRESTRoots, traverser.APIScope(operations), operations))).To(BeEmpty())
+		Expect(traverse(recurser.NewZAPIRecurser(callGraph, workDir, traverser.Roots{"api.RestClient.VolumeCreate"}, traverser.APIScope(operations), operations))).To(BeEmpty())
+	})
+
+	It("should attribute REST APIs to every root reaching them, even through functions already visited", func() {
+		root := function("api/ontap_rest.go", "VolumeCreate")
+		other := function("api/ontap_rest.go", "VolumeCreateDefault")
+		iface := function("api/volumes.go", "VolumeCreate")
+		client := function("storage/volume_client.go", "VolumeCreate")
+		other.Calls = []requests.CallHierarchyItem{root.Item}
+		root.Calls = []requests.CallHierarchyItem{iface.Item}
+		iface.Implementations = []requests.Location{client.Location()}
+		server.Script(root, other, iface, client)
+
+		restAPIs := traverse(recurser.NewRESTRecurser(callGraph, workDir, traverser.DefaultRESTRoots, traverser.APIScope(operations), operations))
+
+		Expect(restAPIs).To(HaveKey(functionID(client)))
+		Expect(restAPIs[functionID(client)].Roots).To(ConsistOf(functionID(root), functionID(other)))
+		// One chain per root, sorted by root, whichever root the walk reached the client from first.
+		Expect(restAPIs[functionID(client)].Chains).To(Equal([][]string{
+			{functionID(other), functionID(root), functionID(iface), functionID(client)},
+			{functionID(root), functionID(iface), functionID(client)},
+		}))
+	})
+
+	It("should find ZAPI commands", func() {
+		root := function("api/ontap_zapi.go", "VolumeCreate")
+		constructor := function("azgo/api-volume-create.go", "NewVolumeCreateRequest")
+		executeUsing := function("azgo/api-volume-create.go", "ExecuteUsing")
+		root.Calls = []requests.CallHierarchyItem{constructor.Item, executeUsing.Item}
+		server.Script(root, constructor, executeUsing)
+
+		zapiCommands := traverse(recurser.NewZAPIRecurser(callGraph, workDir, traverser.DefaultZAPIRoots, traverser.APIScope(operations), operations))
+
+		Expect(zapiCommands).To(HaveLen(1))
+		Expect(zapiCommands).To(Equal(map[string]traverser.Finding{functionID(executeUsing): {
+			API:    []string{"volume-create"},
+			Chains: [][]string{{functionID(root), functionID(executeUsing)}},
+			Roots:  []string{functionID(root)},
+		}}))
+	})
+
+	It("should find the callers of REST APIs, through the interfaces they implement", func() {
+		root := function("api/ontap_rest.go", "VolumeCreate")
+		iface := function("api/volumes.go", "VolumeCreate")
+		client := function("storage/volume_client.go", "VolumeCreate")
+		root.Calls = []requests.CallHierarchyItem{iface.Item}
+		client.Implementations = []requests.Location{iface.Location()}
+		server.Script(root, iface, client)
+
+		restAPIs := traverse(recurser.NewRESTCallersRecurser(callGraph, traverser.ModuleScope(trident), operations))
+
+		Expect(restAPIs).To(Equal(map[string]traverser.Finding{
+			functionID(client): {API: []string{"POST", "/storage/volumes"}, Callers: []string{functionID(root)}},
+		}))
+	})
+
+	It("should find the callers of ZAPI commands", func() {
+		root := function("api/ontap_zapi.go", "VolumeCreate")
+		executeUsing := function("azgo/api-volume-create.go", "ExecuteUsing")
+		root.Calls = []requests.CallHierarchyItem{executeUsing.Item}
+		server.Script(root, executeUsing)
+
+		zapiCommands := traverse(recurser.NewZAPICallersRecurser(callGraph, traverser.ModuleScope(trident), operations))
+
+		Expect(zapiCommands).To(Equal(map[string]traverser.Finding{
+			functionID(executeUsing): {API: []string{"volume-create"}, Callers: []string{functionID(root)}},
+		}))
+	})
+
+	It("should not follow calls outside of the ONTAP api packages", func() {
+		root := function("api/ontap_rest.go", "VolumeCreate")
+		client := function("storage/volume_client.go", "VolumeCreate")
+		client.Item.Detail = "github.com/other/module • volume_client.go"
+		root.Calls = []requests.CallHierarchyItem{client.Item}
+		server.Script(root, client)
+
+		Expect(traverse(recurser.NewRESTRecurser(callGraph, workDir, traverser.DefaultRESTRoots, traverser.APIScope(operations), operations))).To(BeEmpty())
+	})
+
+	It("should not follow calls into packages excluded from the scope", func() {
+		root := function("api/ontap_rest.go", "VolumeCreate")
+		client := function("storage/volume_client.go", "VolumeCreate")
+		client.Item.Detail = ontapAPI + "/rest/client/storage • volume_client.go"
+		root.Calls = []requests.CallHierarchyItem{client.Item}
+		server.Script(root, client)
+
+		scope := traverser.APIScope(operations)
+		scope.Exclusions = []string{ontapAPI + "/rest"}
+		Expect(traverse(recurser.NewRESTRecurser(callGraph, workDir, traverser.DefaultRESTRoots, scope, operations))).To(BeEmpty())
+	})
+})
@@ -4,7 +4,6 @@ import (
 	"bufio"
 	"bytes"
 	"context"
-	"fmt"
 	"go/ast"
 	"go/printer"
 	"go/token"
@@ -15,6 +14,7 @@ import (
 	"github.com/theshashankpal/api-collector/callgraph"
 	"github.com/theshashankpal/api-collector/loader"
 	. "github.com/theshashankpal/api-collector/logger"
+	. "github.com/theshashankpal/api-collector/traverser"
 )
 
 var rr = LogFields{Key: "layer", Value: "rest-dfs-recurser"}
@@ -23,62 +23,66 @@ type RESTRecurser struct {
 	fset *token.FileSet    // Can fset var be shared ?
 	pkgs []*loader.Package // Can package var be shared?
 
-	// Callgraph can be shared between rest and zapi recurser
-	callGraph   callgraph.CallGraph
-	callgraphMU *sync.Mutex
+	// The walk starts from the functions the roots select, in the packages of workDir.
+	workDir string
+	roots   Roots
+	// scope is the packages the walk goes down to.
+	scope Scope
+	// operations are the packages of the functions issuing the ONTAP calls, pkgPaths the package of every file.
+	operations Operations
+	pkgPaths   map[string]string
+
+	// Callgraph is shared between rest and zapi recurser, it is safe for concurrent use
+	callGraph callgraph.CallGraph
 
 	visited      map[string]struct{}
 	visitedMutex *sync.Mutex
 
-	restAPIs      map[string][]string
+	restAPIs      map[string]Finding
 	restAPIsMutex *sync.Mutex
 
+	calls *calls
+
 	// Don't need a mutex for fileMap, as it is read-only
 	fileMap map[string]*ast.File
 	wg      *sync.WaitGroup
 }
 
-func NewRESTRecurser(callGraph callgraph.CallGraph, callGraphMU *sync.Mutex) *RESTRecurser {
+func NewRESTRecurser(callGraph callgraph.CallGraph, workDir string, roots Roots, scope Scope,
+	operations Operations) *RESTRecurser {
 	return &RESTRecurser{
+		workDir:       workDir,
+		roots:         roots,
+		operations:    operations,
+		scope:         scope,
 		callGraph:     callGraph,
-		callgraphMU:   callGraphMU,
 		visited:       make(map[string]struct{}),
 		visitedMutex:  new(sync.Mutex),
-		restAPIs:      make(map[string][]string),
+		restAPIs:      make(map[string]Finding),
 		restAPIsMutex: new(sync.Mutex),
+		calls:         newCalls(),
 		wg:            new(sync.WaitGroup),
 	}
 }
 
-func (r *RESTRecurser) Traverse(ctx context.Context, restAPIsMapChan chan map[string][]string) {
+func (r *RESTRecurser) Traverse(ctx context.Context, restAPIsMapChan chan map[string]Finding) {
 	go func() {
-		for _, pkg := range r.pkgs {
-			if strings.Contains(pkg.PkgPath, "github.com/netapp/trident/storage_drivers/ontap/api") {
-				for _, file := range pkg.Syntax {
-					filePath := pkg.Fset.File(file.Package).Name()
-					if strings.Contains(filePath, "storage_drivers/ontap/api/ontap_rest.go") {
-						fileSet := pkg.Fset
-						ast.Inspect(file, func(node ast.Node) bool {
-							switch typeNode := node.(type) {
-							case *ast.FuncDecl:
-								funcPos := fileSet.Position(typeNode.Name.Pos())
-								line := funcPos.Line
-								character := funcPos.Column
-								filePath = funcPos.Filename
-								r.fset = fileSet
-								r.wg.Add(1)
-								//Indexing starts from 1, hence minus 1.
-								go r.traverseRecursively(ctx, filePath, line-1, character-1, typeNode.Name.Name)
-								return false
-							}
-							return true
-						})
-						break
-					}
-				}
-			}
+		// The loader shares its file set among all packages, it is set before any walk reads it.
+		if len(r.pkgs) > 0 {
+			r.fset = r.pkgs[0].Fset
+		}
+		r.pkgPaths = PackagePaths(r.pkgs)
+
+		roots, unmatched := r.roots.Select(r.workDir, r.pkgs)
+		if len(unmatched) > 0 {
+			Log(ctx, rr).Error().Strs("roots", unmatched).Msg("Roots matching no function, mistyped or outside the work directory")
+		}
+		for _, root := range roots {
+			r.wg.Add(1)
+			go r.traverseRecursively(ctx, root.FilePath, root.Line, root.Character, root.FunctionName, nil)
 		}
 		r.wg.Wait()
+		r.calls.attribute(r.restAPIs)
 		restAPIsMapChan <- r.restAPIs
 	}()
 }
@@ -90,10 +94,14 @@ First time we reach jobGet through another function with depth 2
 then we cannot explore its callees as they will be depth 3, and we're returning in depth 3.
 And afterward, when we actually reach jobGet with depth 0, it has been already visited.
 */
-func (r *RESTRecurser) traverseRecursively(ctx context.Context, filePath string, line, character int, functionName string) {
+func (r *RESTRecurser) traverseRecursively(ctx context.Context, filePath string, line, character int, functionName string,
+	chain []string) {
 	defer r.wg.Done()
 
-	functionID := fmt.Sprintf("%s:%d:%d:%s", filePath, line, character, functionName)
+	functionID := Function{FilePath: filePath, Line: line, Character: character, FunctionName: functionName}.ID()
+	// chain holds the functions from the root down to this one, each callee getting its own copy.
+	chain = append(chain[:len(chain):len(chain)], functionID)
+	r.calls.add(chain)
 
 	r.visitedMutex.Lock()
 	if _, ok := r.visited[functionID]; ok {
@@ -114,23 +122,20 @@ func (r *RESTRecurser) traverseRecursively(ctx context.Context, filePath string,
 	// where the actual implementation is.
 	// Also, functionID will be different in that case, as filePath will be different, so if the above is the case,
 	// we'll be visiting it and not returning early.
-	if strings.Contains(filePath, "ontap/api/rest/client") && strings.HasSuffix(filePath, "client.go") {
+	if r.operations.IsREST(r.pkgPaths[filePath], filePath) {
 		lis := r.restScraper(ctx, filePath, functionName)
 		// We've found what we were looking for, so we can return
 		// otherwise continue with finding its callees.
 		if len(lis) != 0 {
 			r.restAPIsMutex.Lock()
-			if _, ok := r.restAPIs[functionID]; !ok {
-				r.restAPIs[functionID] = lis
-			}
+			// Its chains are found once the walk is over, by calls.attribute.
+			r.restAPIs[functionID] = Finding{API: lis}
 			r.restAPIsMutex.Unlock()
 			return
 		}
 	}
 
-	r.callgraphMU.Lock()
 	outgoingCallsChan := r.callGraph.OutgoingCalls(ctx, filePath, line, character)
-	r.callgraphMU.Unlock()
 	outgoingCalls := <-outgoingCallsChan
 	if outgoingCalls.Error != nil {
 		Log(ctx).Error().
@@ -146,53 +151,19 @@ func (r *RESTRecurser) traverseRecursively(ctx context.Context, filePath string,
 
 	// outGoingCalls can be of length 0, indicating that we might have encountered an interface.
 	if len(outgoingCalls.Result) == 0 {
-		isInterface := false
-
 		// Get the file
 		file, ok := r.fileMap[filePath]
 		if !ok {
 			Log(ctx, rr).Panic().Str("filePath", filePath).Stack().Msg("File not found in fileMap")
 		}
 
-		var lastTypeSpec *ast.TypeSpec
-		ast.Inspect(file, func(n ast.Node) bool {
-			switch node := n.(type) {
-			case *ast.TypeSpec:
-				lastTypeSpec = node // Update lastTypeSpec with the current *ast.TypeSpec node
-			case *ast.InterfaceType:
-				if lastTypeSpec != nil {
-					var interfaceType *ast.InterfaceType
-					if interfaceType, ok = lastTypeSpec.Type.(*ast.InterfaceType); !ok {
-						return true
-					}
-
-					for _, method := range interfaceType.Methods.List {
-						// method.Names represent: field/method/(type) parameter names; or nil
-						// here it is just a method, so Names[0] should do
-						if len(method.Names) > 0 {
-							interfacName := method.Names[0].Name
-							linePos := method.Names[0].Pos()
-							lineInteface := r.fset.Position(linePos).Line
-							if interfacName == functionName && line == (lineInteface-1) {
-								isInterface = true
-								return false
-							}
-						}
-					}
-				}
-			}
-			return true
-		})
-
-		if isInterface == true {
-			r.callgraphMU.Lock()
+		if IsInterfaceMethod(r.fset, file, functionName, line) {
 			implementationsChan := r.callGraph.Implementations(ctx, filePath, line, character)
-			r.callgraphMU.Unlock()
 			implementation := <-implementationsChan
 			if implementation.Error != nil {
 				Log(ctx, rr).Error().
-					Int("ErrorCode", outgoingCalls.Error.Code).
-					Str("Error", outgoingCalls.Error.Message).
+					Int("ErrorCode", implementation.Error.Code).
+					Str("Error", implementation.Error.Message).
 					Str("FilePath", filePath).
 					Str("FunctionName", functionName).
 					Int("Character", character).
@@ -207,18 +178,14 @@ func (r *RESTRecurser) traverseRecursively(ctx context.Context, filePath string,
 				filePath = impl.Uri
 				filePath = strings.ReplaceAll(filePath, "file://", "")
 				r.wg.Add(1)
-				go r.traverseRecursively(ctx, filePath, impl.Range.Start.Line, impl.Range.Start.Character, functionName)
+				go r.traverseRecursively(ctx, filePath, impl.Range.Start.Line, impl.Range.Start.Character, functionName, chain)
 			}
 		}
 	}
 
 	for _, call := range outgoingCalls.Result {
 		// Don't want to explore callee of other packages
-		if !strings.Contains(call.To.Detail, "github.com/netapp/trident") {
-			continue
-		}
-
-		if !strings.Contains(call.To.Detail, "github.com/netapp/trident/storage_drivers/ontap/api") {
+		if !r.scope.ContainsDetail(call.To.Detail) {
 			continue
 		}
 
@@ -227,7 +194,7 @@ func (r *RESTRecurser) traverseRecursively(ctx context.Context, filePath string,
 		filePath = call.To.Uri
 		filePath = strings.ReplaceAll(filePath, "file://", "")
 		r.wg.Add(1)
-		go r.traverseRecursively(ctx, filePath, line, character, call.To.Name)
+		go r.traverseRecursively(ctx, filePath, line, character, call.To.Name, chain)
 	}
 }
 
@@ -239,6 +206,11 @@ func (r *RESTRecurser) restScraper(ctx context.Context, filePath, functionName s
 		Log(ctx, rr).Panic().Str("filePath", filePath).Stack().Msg("File not found in fileMap")
 	}
 
+	return ScrapeRESTAPI(ctx, r.fset, file, functionName)
+}
+
+// ScrapeRESTAPI returns the method and the path pattern of the client operation built by the function, nil if none.
+func ScrapeRESTAPI(ctx context.Context, fset *token.FileSet, file *ast.File, functionName string) []string {
 	var method string
 	var api string
 
@@ -256,7 +228,7 @@ func (r *RESTRecurser) restScraper(ctx context.Context, filePath, functionName s
 		var buf bytes.Buffer
 
 		// Reading the function body
-		err := printer.Fprint(&buf, r.fset, funcDecl)
+		err := printer.Fprint(&buf, fset, funcDecl)
 		if err != nil {
 			panic(err)
 		}
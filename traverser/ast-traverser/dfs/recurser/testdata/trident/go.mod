@@ -0,0 +1,3 @@
+module github.com/netapp/trident
+
+go 1.22
@@ -0,0 +1,15 @@
+package azgo
+
+import "encoding/xml"
+
+type VolumeCreateRequest struct {
+	XMLName xml.Name `xml:"volume-create"`
+}
+
+func NewVolumeCreateRequest() *VolumeCreateRequest {
+	return &VolumeCreateRequest{}
+}
+
+func (o *VolumeCreateRequest) ExecuteUsing() error {
+	return nil
+}
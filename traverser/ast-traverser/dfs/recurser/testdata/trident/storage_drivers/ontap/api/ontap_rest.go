@@ -0,0 +1,15 @@
+package api
+
+import "github.com/netapp/trident/storage_drivers/ontap/api/rest/client/storage"
+
+type RestClient struct {
+	volumes Volumes
+}
+
+func (c *RestClient) VolumeCreate() error {
+	return c.volumes.VolumeCreate(&storage.VolumeCreateParams{})
+}
+
+func (c *RestClient) VolumeCreateDefault() error {
+	return c.VolumeCreate()
+}
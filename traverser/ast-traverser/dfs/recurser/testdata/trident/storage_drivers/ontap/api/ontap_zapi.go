@@ -0,0 +1,9 @@
+package api
+
+import "github.com/netapp/trident/storage_drivers/ontap/api/azgo"
+
+type Client struct{}
+
+func (d Client) VolumeCreate() error {
+	return azgo.NewVolumeCreateRequest().ExecuteUsing()
+}
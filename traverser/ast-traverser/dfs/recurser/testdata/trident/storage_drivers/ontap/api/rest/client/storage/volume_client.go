@@ -0,0 +1,20 @@
+package storage
+
+type VolumeCreateParams struct{}
+
+type ClientOperation struct {
+	ID          string
+	Method      string
+	PathPattern string
+}
+
+type Client struct{}
+
+func (a *Client) VolumeCreate(params *VolumeCreateParams) error {
+	_ = &ClientOperation{
+		ID:          "volume_create",
+		Method:      "POST",
+		PathPattern: "/storage/volumes",
+	}
+	return nil
+}
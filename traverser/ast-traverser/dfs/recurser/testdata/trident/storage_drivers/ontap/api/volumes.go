@@ -0,0 +1,7 @@
+package api
+
+import "github.com/netapp/trident/storage_drivers/ontap/api/rest/client/storage"
+
+type Volumes interface {
+	VolumeCreate(params *storage.VolumeCreateParams) error
+}
@@ -0,0 +1,5 @@
+package yaml
+
+func Marshal(in interface{}) ([]byte, error) {
+	return nil, nil
+}
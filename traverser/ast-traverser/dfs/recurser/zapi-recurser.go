@@ -2,7 +2,6 @@ package recurser
 
 import (
 	"context"
-	"fmt"
 	"go/ast"
 	"go/token"
 	"strings"
@@ -11,6 +10,7 @@ import (
 	"github.com/theshashankpal/api-collector/callgraph"
 	"github.com/theshashankpal/api-collector/loader"
 	. "github.com/theshashankpal/api-collector/logger"
+	. "github.com/theshashankpal/api-collector/traverser"
 )
 
 var zr = LogFields{Key: "layer", Value: "zapi-dfs-recurser"}
@@ -19,62 +19,66 @@ type ZAPIRecurser struct {
 	fset *token.FileSet    // Can fset var be shared ?
 	pkgs []*loader.Package // Can package var be shared?
 
-	// Callgraph can be shared between rest and zapi recurser
-	callGraph   callgraph.CallGraph
-	callgraphMU *sync.Mutex
+	// The walk starts from the functions the roots select, in the packages of workDir.
+	workDir string
+	roots   Roots
+	// scope is the packages the walk goes down to.
+	scope Scope
+	// operations are the packages of the functions issuing the ONTAP calls, pkgPaths the package of every file.
+	operations Operations
+	pkgPaths   map[string]string
+
+	// Callgraph is shared between rest and zapi recurser, it is safe for concurrent use
+	callGraph callgraph.CallGraph
 
 	visited      map[string]struct{}
 	visitedMutex *sync.Mutex
 
-	zapiCMDs   map[string][]string
+	zapiCMDs   map[string]Finding
 	zapiCMDsMU *sync.Mutex
 
+	calls *calls
+
 	// Don't need a mutex for fileMap, as it is read-only
 	fileMap map[string]*ast.File
 	wg      *sync.WaitGroup
 }
 
-func NewZAPIRecurser(callGraph callgraph.CallGraph, callGraphMU *sync.Mutex) *ZAPIRecurser {
+func NewZAPIRecurser(callGraph callgraph.CallGraph, workDir string, roots Roots, scope Scope,
+	operations Operations) *ZAPIRecurser {
 	return &ZAPIRecurser{
+		workDir:      workDir,
+		roots:        roots,
+		operations:   operations,
+		scope:        scope,
 		callGraph:    callGraph,
-		callgraphMU:  callGraphMU,
 		visited:      make(map[string]struct{}),
 		visitedMutex: new(sync.Mutex),
-		zapiCMDs:     make(map[string][]string),
+		zapiCMDs:     make(map[string]Finding),
 		zapiCMDsMU:   new(sync.Mutex),
+		calls:        newCalls(),
 		wg:           new(sync.WaitGroup),
 	}
 }
 
-func (z *ZAPIRecurser) Traverse(ctx context.Context, zapiCommandsChan chan map[string][]string) {
+func (z *ZAPIRecurser) Traverse(ctx context.Context, zapiCommandsChan chan map[string]Finding) {
 	go func() {
-		for _, pkg := range z.pkgs {
-			if strings.Contains(pkg.PkgPath, "github.com/netapp/trident/storage_drivers/ontap/api") {
-				for _, file := range pkg.Syntax {
-					filePath := pkg.Fset.File(file.Package).Name()
-					if strings.Contains(filePath, "storage_drivers/ontap/api/ontap_zapi.go") {
-						fileSet := pkg.Fset
-						ast.Inspect(file, func(node ast.Node) bool {
-							switch typeNode := node.(type) {
-							case *ast.FuncDecl:
-								funcPos := fileSet.Position(typeNode.Name.Pos())
-								line := funcPos.Line
-								character := funcPos.Column
-								filePath = funcPos.Filename
-								z.fset = fileSet
-								z.wg.Add(1)
-								//Indexing starts from 1, hence minus 1.
-								go z.traverseRecursively(ctx, filePath, line-1, character-1, typeNode.Name.Name)
-								return false
-							}
-							return true
-						})
-						break
-					}
-				}
-			}
+		// The loader shares its file set among all packages, it is set before any walk reads it.
+		if len(z.pkgs) > 0 {
+			z.fset = z.pkgs[0].Fset
+		}
+		z.pkgPaths = PackagePaths(z.pkgs)
+
+		roots, unmatched := z.roots.Select(z.workDir, z.pkgs)
+		if len(unmatched) > 0 {
+			Log(ctx, zr).Error().Strs("roots", unmatched).Msg("Roots matching no function, mistyped or outside the work directory")
+		}
+		for _, root := range roots {
+			z.wg.Add(1)
+			go z.traverseRecursively(ctx, root.FilePath, root.Line, root.Character, root.FunctionName, nil)
 		}
 		z.wg.Wait()
+		z.calls.attribute(z.zapiCMDs)
 		zapiCommandsChan <- z.zapiCMDs
 	}()
 }
@@ -86,10 +90,14 @@ First time we reach jobGet through another function with depth 2
 then we cannot explore its callees as they will be depth 3, and we're returning in depth 3.
 And afterward, when we actually reach jobGet with depth 0, it has been already visited.
 */
-func (z *ZAPIRecurser) traverseRecursively(ctx context.Context, filePath string, line, character int, functionName string) {
+func (z *ZAPIRecurser) traverseRecursively(ctx context.Context, filePath string, line, character int, functionName string,
+	chain []string) {
 	defer z.wg.Done()
 
-	functionID := fmt.Sprintf("%s:%d:%d:%s", filePath, line, character, functionName)
+	functionID := Function{FilePath: filePath, Line: line, Character: character, FunctionName: functionName}.ID()
+	// chain holds the functions from the root down to this one, each callee getting its own copy.
+	chain = append(chain[:len(chain):len(chain)], functionID)
+	z.calls.add(chain)
 
 	z.visitedMutex.Lock()
 	if _, ok := z.visited[functionID]; ok {
@@ -111,32 +119,20 @@ func (z *ZAPIRecurser) traverseRecursively(ctx context.Context, filePath string,
 	// Also, functionID will be different in that case, as filePath will be different, so if the above is the case,
 	// we'll be visiting it and not returning early.
 
-	if strings.Contains(filePath, "ontap/api/azgo") {
-		pathParts := strings.Split(filePath, "/")
-		fileEnd := pathParts[len(pathParts)-1]
-		if strings.HasPrefix(fileEnd, "api-") {
-			if functionName == "ExecuteUsing" {
-				command := z.zapiScraper(ctx, filePath, functionName)
-				// We've found what we were looking for, so we can return
-				// otherwise continue with finding its callees.
-				if len(command) != 0 {
-					z.zapiCMDsMU.Lock()
-					if _, ok := z.zapiCMDs[functionID]; !ok {
-						z.zapiCMDs[functionID] = command
-					}
-					z.zapiCMDsMU.Unlock()
-					return
-				}
-			}
+	if z.operations.IsZAPI(z.pkgPaths[filePath], filePath, functionName) {
+		command := z.zapiScraper(ctx, filePath, functionName)
+		// We've found what we were looking for, so we can return
+		// otherwise continue with finding its callees.
+		if len(command) != 0 {
+			z.zapiCMDsMU.Lock()
+			// Its chains are found once the walk is over, by calls.attribute.
+			z.zapiCMDs[functionID] = Finding{API: command}
+			z.zapiCMDsMU.Unlock()
+			return
 		}
 	}
 
-	//mutex.Unlock()
-	//fmt.Printf("Visiting function: %s in file: %s\n", functionName, filePath)
-
-	z.callgraphMU.Lock()
 	outgoingCallsChan := z.callGraph.OutgoingCalls(ctx, filePath, line, character)
-	z.callgraphMU.Unlock()
 	outgoingCalls := <-outgoingCallsChan
 	if outgoingCalls.Error != nil {
 		Log(ctx, zr).Error().
@@ -152,53 +148,19 @@ func (z *ZAPIRecurser) traverseRecursively(ctx context.Context, filePath string,
 
 	// outGoingCalls can be of length 0, indicating that we might have encountered an interface.
 	if len(outgoingCalls.Result) == 0 {
-		isInterface := false
-
 		// Get the file
 		file, ok := z.fileMap[filePath]
 		if !ok {
 			Log(ctx, zr).Panic().Str("filePath", filePath).Stack().Msg("File not found in fileMap")
 		}
 
-		var lastTypeSpec *ast.TypeSpec
-		ast.Inspect(file, func(n ast.Node) bool {
-			switch node := n.(type) {
-			case *ast.TypeSpec:
-				lastTypeSpec = node // Update lastTypeSpec with the current *ast.TypeSpec node
-			case *ast.InterfaceType:
-				if lastTypeSpec != nil {
-					var interfaceType *ast.InterfaceType
-					if interfaceType, ok = lastTypeSpec.Type.(*ast.InterfaceType); !ok {
-						return true
-					}
-
-					for _, method := range interfaceType.Methods.List {
-						// method.Names represent: field/method/(type) parameter names; or nil
-						// here it is just a method, so Names[0] should do
-						if len(method.Names) > 0 {
-							interfacName := method.Names[0].Name
-							linePos := method.Names[0].Pos()
-							lineInteface := z.fset.Position(linePos).Line
-							if interfacName == functionName && line == (lineInteface-1) {
-								isInterface = true
-								return false
-							}
-						}
-					}
-				}
-			}
-			return true
-		})
-
-		if isInterface == true {
-			z.callgraphMU.Lock()
+		if IsInterfaceMethod(z.fset, file, functionName, line) {
 			implementationsChan := z.callGraph.Implementations(ctx, filePath, line, character)
-			z.callgraphMU.Unlock()
 			implementation := <-implementationsChan
 			if implementation.Error != nil {
-				Log(ctx).Error().
-					Int("ErrorCode", outgoingCalls.Error.Code).
-					Str("Error", outgoingCalls.Error.Message).
+				Log(ctx, zr).Error().
+					Int("ErrorCode", implementation.Error.Code).
+					Str("Error", implementation.Error.Message).
 					Str("FilePath", filePath).
 					Str("FunctionName", functionName).
 					Int("Character", character).
@@ -213,18 +175,14 @@ func (z *ZAPIRecurser) traverseRecursively(ctx context.Context, filePath string,
 				filePath = impl.Uri
 				filePath = strings.ReplaceAll(filePath, "file://", "")
 				z.wg.Add(1)
-				go z.traverseRecursively(ctx, filePath, impl.Range.Start.Line, impl.Range.Start.Character, functionName)
+				go z.traverseRecursively(ctx, filePath, impl.Range.Start.Line, impl.Range.Start.Character, functionName, chain)
 			}
 		}
 	}
 
 	for _, call := range outgoingCalls.Result {
 		// Don't want to explore callee of other packages
-		if !strings.Contains(call.To.Detail, "github.com/netapp/trident") {
-			continue
-		}
-
-		if !strings.Contains(call.To.Detail, "github.com/netapp/trident/storage_drivers/ontap/api") {
+		if !z.scope.ContainsDetail(call.To.Detail) {
 			continue
 		}
 
@@ -233,7 +191,7 @@ func (z *ZAPIRecurser) traverseRecursively(ctx context.Context, filePath string,
 		filePath = call.To.Uri
 		filePath = strings.ReplaceAll(filePath, "file://", "")
 		z.wg.Add(1)
-		go z.traverseRecursively(ctx, filePath, line, character, call.To.Name)
+		go z.traverseRecursively(ctx, filePath, line, character, call.To.Name, chain)
 	}
 }
 
@@ -244,6 +202,13 @@ func (z *ZAPIRecurser) zapiScraper(ctx context.Context, filePath string, functio
 	if !ok {
 		Log(ctx, zr).Panic().Str("filePath", filePath).Stack().Msg("File not found in fileMap")
 	}
+
+	return ScrapeZAPICommand(ctx, file, functionName)
+}
+
+// ScrapeZAPICommand returns the XML name of the request whose ExecuteUsing method is the function,
+// an empty name if none.
+func ScrapeZAPICommand(ctx context.Context, file *ast.File, functionName string) []string {
 	var command = make([]string, 1)
 	ast.Inspect(file, func(node ast.Node) bool {
 		typeNode, ok := node.(*ast.FuncDecl)
@@ -0,0 +1,44 @@
+package traverser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Function is a position in the call-graph, zero-based as the call-graph expects, as the walks pass it around.
+type Function struct {
+	FilePath     string
+	Line         int
+	Character    int
+	FunctionName string
+}
+
+// ID returns the functionID of the function, "file:line:character:name", which keys the results of the walks.
+func (f Function) ID() string {
+	return fmt.Sprintf("%s:%d:%d:%s", f.FilePath, f.Line, f.Character, f.FunctionName)
+}
+
+// ParseFunctionID turns a functionID, as ID formats it, back into its function. It is parsed from the right,
+// the file path being the only part which may hold a ':'.
+func ParseFunctionID(functionID string) (Function, error) {
+	var parts [3]string
+	rest := functionID
+	for i := len(parts) - 1; i >= 0; i-- {
+		sep := strings.LastIndex(rest, ":")
+		if sep < 0 {
+			return Function{}, fmt.Errorf("#ParseFunctionID: malformed functionID %q", functionID)
+		}
+		rest, parts[i] = rest[:sep], rest[sep+1:]
+	}
+
+	line, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return Function{}, fmt.Errorf("#ParseFunctionID: malformed line of functionID %q -> %w", functionID, err)
+	}
+	character, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return Function{}, fmt.Errorf("#ParseFunctionID: malformed character of functionID %q -> %w", functionID, err)
+	}
+	return Function{FilePath: rest, Line: line, Character: character, FunctionName: strings.TrimSpace(parts[2])}, nil
+}
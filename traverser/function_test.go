@@ -0,0 +1,20 @@
+package traverser_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/traverser"
+)
+
+var _ = Describe("Function", func() {
+	It("should parse a functionID back from the right, whatever its file path holds", func() {
+		function := traverser.Function{FilePath: `C:\trident\api:v2\ontap_rest.go`, Line: 41, Character: 5, FunctionName: "VolumeCreate"}
+		Expect(traverser.ParseFunctionID(function.ID())).To(Equal(function))
+
+		_, err := traverser.ParseFunctionID("ontap_rest.go:41:VolumeCreate")
+		Expect(err).To(HaveOccurred())
+		_, err = traverser.ParseFunctionID("ontap_rest.go:41:x:VolumeCreate")
+		Expect(err).To(HaveOccurred())
+	})
+})
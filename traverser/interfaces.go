@@ -0,0 +1,26 @@
+package traverser
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// IsInterfaceMethod tells whether the function, declared in the file at the zero-based line, is a method of
+// an interface type. It then has no outgoing calls, its implementations being what it leads to.
+func IsInterfaceMethod(fset *token.FileSet, file *ast.File, functionName string, line int) bool {
+	found := false
+	ast.Inspect(file, func(n ast.Node) bool {
+		if interfaceType, ok := n.(*ast.InterfaceType); ok {
+			for _, method := range interfaceType.Methods.List {
+				// method.Names represent: field/method/(type) parameter names; or nil
+				// here it is just a method, so Names[0] should do
+				if len(method.Names) > 0 && method.Names[0].Name == functionName &&
+					fset.Position(method.Names[0].Pos()).Line-1 == line {
+					found = true
+				}
+			}
+		}
+		return !found
+	})
+	return found
+}
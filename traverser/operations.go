@@ -0,0 +1,63 @@
+package traverser
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/theshashankpal/api-collector/loader"
+)
+
+// Operations are the packages holding the functions which issue the ONTAP calls, by import path:
+//   - REST, the go-swagger clients, whose methods declared in *client.go files are the REST operations
+//   - ZAPI, the azgo requests, whose ExecuteUsing methods declared in api-*.go files are the ZAPI commands
+type Operations struct {
+	REST []string `json:"rest"`
+	ZAPI []string `json:"zapi"`
+}
+
+// DefaultOperations are the operation packages of the module, laid out as in Trident.
+func DefaultOperations(modulePath string) Operations {
+	return Operations{
+		REST: []string{modulePath + "/storage_drivers/ontap/api/rest/client"},
+		ZAPI: []string{modulePath + "/storage_drivers/ontap/api/azgo"},
+	}
+}
+
+// IsREST tells whether the function declared in the file of the package is a REST client operation.
+func (o Operations) IsREST(pkgPath, filePath string) bool {
+	return underAny(pkgPath, o.REST) && strings.HasSuffix(filePath, "client.go")
+}
+
+// IsZAPI tells whether the function declared in the file of the package is a ZAPI command.
+func (o Operations) IsZAPI(pkgPath, filePath, functionName string) bool {
+	return functionName == "ExecuteUsing" && underAny(pkgPath, o.ZAPI) && strings.HasPrefix(filepath.Base(filePath), "api-")
+}
+
+// Packages returns the import paths of every operation package.
+func (o Operations) Packages() []string {
+	return append(append([]string{}, o.REST...), o.ZAPI...)
+}
+
+func (o Operations) String() string {
+	return "rest " + strings.Join(o.REST, ",") + ", zapi " + strings.Join(o.ZAPI, ",")
+}
+
+// PackagePaths maps the Go files of the packages to the import path of their package, which tells the operations.
+func PackagePaths(pkgs []*loader.Package) map[string]string {
+	pkgPaths := make(map[string]string)
+	for _, pkg := range pkgs {
+		for _, filePath := range pkg.GoFiles {
+			pkgPaths[filePath] = pkg.PkgPath
+		}
+	}
+	return pkgPaths
+}
+
+func underAny(importPath string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if under(importPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+package traverser_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/traverser"
+)
+
+var _ = Describe("Operations", func() {
+	operations := traverser.Operations{
+		REST: []string{"example.com/ontap/rest/client"},
+		ZAPI: []string{"example.com/ontap/zapi"},
+	}
+
+	It("should tell the REST operations by the package of their client file", func() {
+		Expect(operations.IsREST("example.com/ontap/rest/client/storage", "/src/rest/client/storage/volume_client.go")).To(BeTrue())
+		Expect(operations.IsREST("example.com/ontap/rest/client", "/src/rest/client/ontap_client.go")).To(BeTrue())
+		Expect(operations.IsREST("example.com/ontap/rest/client/storage", "/src/rest/client/storage/volume_parameters.go")).To(BeFalse())
+		Expect(operations.IsREST("example.com/ontap/rest/clients", "/src/rest/clients/volume_client.go")).To(BeFalse())
+		// The file path alone doesn't tell, whatever its layout.
+		Expect(operations.IsREST("example.com/fork/ontap/api/rest/client", "/src/ontap/api/rest/client/volume_client.go")).To(BeFalse())
+	})
+
+	It("should tell the ZAPI commands by the package of their request file", func() {
+		Expect(operations.IsZAPI("example.com/ontap/zapi", "/src/zapi/api-volume-create.go", "ExecuteUsing")).To(BeTrue())
+		Expect(operations.IsZAPI("example.com/ontap/zapi", "/src/zapi/api-volume-create.go", "NewVolumeCreateRequest")).To(BeFalse())
+		Expect(operations.IsZAPI("example.com/ontap/zapi", "/src/zapi/volume.go", "ExecuteUsing")).To(BeFalse())
+		Expect(operations.IsZAPI("example.com/ontap/api/azgo", "/src/ontap/api/azgo/api-volume-create.go", "ExecuteUsing")).To(BeFalse())
+	})
+})
@@ -0,0 +1,206 @@
+package traverser
+
+import (
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/theshashankpal/api-collector/loader"
+)
+
+// Roots are the patterns selecting the functions the walk down starts from, each one being either:
+//   - a Go file, absolute or relative to the work directory, e.g. storage_drivers/ontap/api/ontap_rest.go
+//   - a glob of Go files, in filepath.Match syntax, e.g. storage_drivers/ontap/ontap_*.go
+//   - a package, by its import path or its directory, e.g. github.com/netapp/trident/storage_drivers/ontap
+//   - a function, a type, whose methods are all roots, or a method, qualified by the name or the import path of
+//     its package, e.g. api.RestClient.VolumeCreate, unless a package has that import path, e.g. gopkg.in/yaml.v3
+type Roots []string
+
+var (
+	DefaultRESTRoots = Roots{"storage_drivers/ontap/api/ontap_rest.go"}
+	DefaultZAPIRoots = Roots{"storage_drivers/ontap/api/ontap_zapi.go"}
+)
+
+// Root is a function the walk down starts from.
+type Root = Function
+
+// Holds tells whether the package may declare roots, its syntax then being needed to select them.
+func (r Roots) Holds(workDir string, pkg *loader.Package) bool {
+	workDir = baseDir(workDir)
+	for _, pattern := range r {
+		switch {
+		case isGlob(pattern) || isFile(pattern):
+			for _, filePath := range pkg.GoFiles {
+				if matchFile(workDir, pattern, filePath) {
+					return true
+				}
+			}
+		case matchPackage(workDir, pattern, pkg):
+			return true
+		case isSymbol(pattern):
+			if qualifier, _ := splitSymbol(pattern); qualifies(qualifier, pkg) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Select returns the roots declared in the packages whose syntax is loaded, sorted and once each,
+// along with the patterns which select none of them, most likely mistyped.
+func (r Roots) Select(workDir string, pkgs []*loader.Package) ([]Root, Roots) {
+	workDir = baseDir(workDir)
+	selected := make(map[string]Root)
+	matched := make(map[string]bool)
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			filePath := pkg.Fset.File(file.Package).Name()
+			for _, decl := range file.Decls {
+				funcDecl, ok := decl.(*ast.FuncDecl)
+				if !ok {
+					continue
+				}
+				for _, pattern := range r {
+					if !match(workDir, pattern, pkg, filePath, funcDecl) {
+						continue
+					}
+					matched[pattern] = true
+
+					//Indexing starts from 1, hence minus 1.
+					funcPos := pkg.Fset.Position(funcDecl.Name.Pos())
+					root := Root{funcPos.Filename, funcPos.Line - 1, funcPos.Column - 1, funcDecl.Name.Name}
+					selected[root.ID()] = root
+				}
+			}
+		}
+	}
+
+	var unmatched Roots
+	for _, pattern := range r {
+		if !matched[pattern] {
+			unmatched = append(unmatched, pattern)
+		}
+	}
+
+	roots := make([]Root, 0, len(selected))
+	for _, root := range selected {
+		roots = append(roots, root)
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		if roots[i].FilePath != roots[j].FilePath {
+			return roots[i].FilePath < roots[j].FilePath
+		}
+		return roots[i].Line < roots[j].Line
+	})
+	return roots, unmatched
+}
+
+// match tells whether the pattern selects the function declared in the file of the package.
+func match(workDir, pattern string, pkg *loader.Package, filePath string, funcDecl *ast.FuncDecl) bool {
+	switch {
+	case isGlob(pattern) || isFile(pattern):
+		return matchFile(workDir, pattern, filePath)
+	case matchPackage(workDir, pattern, pkg):
+		return true
+	case isSymbol(pattern):
+		qualifier, names := splitSymbol(pattern)
+		if !qualifies(qualifier, pkg) {
+			return false
+		}
+		receiver := receiverName(funcDecl)
+		switch len(names) {
+		case 1:
+			// Either a function, or a type whose methods are all roots.
+			return (receiver == "" && names[0] == funcDecl.Name.Name) || names[0] == receiver
+		case 2:
+			return names[0] == receiver && names[1] == funcDecl.Name.Name
+		}
+	}
+	return false
+}
+
+// baseDir returns the absolute directory the work directory names, it may be given as a loader pattern, e.g. trident/...
+func baseDir(workDir string) string {
+	workDir = strings.TrimSuffix(workDir, "/...")
+	if absDir, err := filepath.Abs(workDir); err == nil {
+		return absDir
+	}
+	return workDir
+}
+
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, "*?[")
+}
+
+func isFile(pattern string) bool {
+	return strings.HasSuffix(pattern, ".go")
+}
+
+// isSymbol tells whether the pattern may be a symbol, its last element holding a dot. The last element of
+// a versioned import path holds one too, e.g. gopkg.in/yaml.v3, so packages are matched first.
+func isSymbol(pattern string) bool {
+	last := pattern[strings.LastIndex(pattern, "/")+1:]
+	return last != "." && last != ".." && strings.Contains(last, ".")
+}
+
+// splitSymbol splits pkg.Type.Method, or pkg.Func, into the package qualifier and the names following it.
+func splitSymbol(pattern string) (string, []string) {
+	slash := strings.LastIndex(pattern, "/")
+	parts := strings.Split(pattern[slash+1:], ".")
+	return pattern[:slash+1] + parts[0], parts[1:]
+}
+
+// qualifies tells whether the qualifier is the import path of the package, or its name.
+func qualifies(qualifier string, pkg *loader.Package) bool {
+	if strings.Contains(qualifier, "/") {
+		return qualifier == pkg.PkgPath
+	}
+	return qualifier == pkg.Name
+}
+
+func matchFile(workDir, pattern, filePath string) bool {
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(workDir, pattern)
+	}
+	matched, err := filepath.Match(pattern, filePath)
+	return err == nil && matched
+}
+
+func matchPackage(workDir, pattern string, pkg *loader.Package) bool {
+	if pattern == pkg.PkgPath {
+		return true
+	}
+	if len(pkg.GoFiles) == 0 {
+		return false
+	}
+	if !filepath.IsAbs(pattern) {
+		pattern = filepath.Join(workDir, pattern)
+	}
+	return filepath.Clean(pattern) == filepath.Dir(pkg.GoFiles[0])
+}
+
+// receiverName returns the name of the type the method is declared on, empty for a function.
+func receiverName(funcDecl *ast.FuncDecl) string {
+	if funcDecl.Recv == nil || len(funcDecl.Recv.List) == 0 {
+		return ""
+	}
+
+	expr := funcDecl.Recv.List[0].Type
+	for {
+		switch typeExpr := expr.(type) {
+		case *ast.StarExpr:
+			expr = typeExpr.X
+		case *ast.IndexExpr:
+			expr = typeExpr.X
+		case *ast.IndexListExpr:
+			expr = typeExpr.X
+		case *ast.ParenExpr:
+			expr = typeExpr.X
+		case *ast.Ident:
+			return typeExpr.Name
+		default:
+			return ""
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package traverser_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"golang.org/x/tools/go/packages"
+
+	"github.com/theshashankpal/api-collector/loader"
+	"github.com/theshashankpal/api-collector/traverser"
+)
+
+var _ = Describe("Roots", func() {
+	var (
+		workDir string
+		pkgs    []*loader.Package
+	)
+
+	// selected returns the roots the patterns select, as name and file.
+	selected := func(roots traverser.Roots) []string {
+		var names []string
+		selectedRoots, unmatched := roots.Select(workDir, pkgs)
+		Expect(unmatched).To(BeEmpty())
+		for _, root := range selectedRoots {
+			names = append(names, root.FunctionName+" "+filepath.Base(root.FilePath))
+		}
+		return names
+	}
+
+	BeforeEach(func() {
+		var err error
+		workDir, err = filepath.Abs("ast-traverser/dfs/recurser/testdata/trident")
+		Expect(err).ToNot(HaveOccurred())
+
+		pkgs, err = loader.LoadRootsWithConfig(&packages.Config{Dir: workDir}, "./...")
+		Expect(err).ToNot(HaveOccurred())
+		for _, pkg := range pkgs {
+			pkg.NeedSyntax()
+		}
+	})
+
+	It("should select the functions of files and globs, relative to the work directory", func() {
+		Expect(selected(traverser.DefaultRESTRoots)).To(Equal([]string{"VolumeCreate ontap_rest.go", "VolumeCreateDefault ontap_rest.go"}))
+		Expect(selected(traverser.Roots{"storage_drivers/ontap/api/ontap_*.go"})).To(Equal([]string{
+			"VolumeCreate ontap_rest.go", "VolumeCreateDefault ontap_rest.go", "VolumeCreate ontap_zapi.go",
+		}))
+	})
+
+	It("should select the functions of packages, by import path or directory", func() {
+		Expect(selected(traverser.Roots{"github.com/netapp/trident/storage_drivers/ontap/api/azgo"})).To(Equal([]string{
+			"NewVolumeCreateRequest api-volume-create.go", "ExecuteUsing api-volume-create.go",
+		}))
+		Expect(selected(traverser.Roots{"storage_drivers/ontap/api/rest/client/storage"})).To(Equal([]string{
+			"VolumeCreate volume_client.go",
+		}))
+	})
+
+	It("should select functions, methods and every method of a type, qualified by package name or import path", func() {
+		Expect(selected(traverser.Roots{"azgo.NewVolumeCreateRequest"})).To(Equal([]string{"NewVolumeCreateRequest api-volume-create.go"}))
+		Expect(selected(traverser.Roots{"api.RestClient.VolumeCreateDefault"})).To(Equal([]string{"VolumeCreateDefault ontap_rest.go"}))
+		Expect(selected(traverser.Roots{"github.com/netapp/trident/storage_drivers/ontap/api.Client"})).To(Equal([]string{
+			"VolumeCreate ontap_zapi.go",
+		}))
+	})
+
+	It("should select the functions of packages whose import path looks like a symbol", func() {
+		Expect(selected(traverser.Roots{"github.com/netapp/trident/utils/yaml.v3"})).To(Equal([]string{"Marshal yaml.go"}))
+		Expect(selected(traverser.Roots{"utils/yaml.v3"})).To(Equal([]string{"Marshal yaml.go"}))
+	})
+
+	It("should report the patterns selecting no function", func() {
+		roots, unmatched := traverser.Roots{"api.RestClient.VolumeCreat", "azgo.NewVolumeCreateRequest", "storage_drivers/ontap/nothing_*.go"}.
+			Select(workDir, pkgs)
+		Expect(roots).To(HaveLen(1))
+		Expect(unmatched).To(Equal(traverser.Roots{"api.RestClient.VolumeCreat", "storage_drivers/ontap/nothing_*.go"}))
+	})
+
+	It("should tell the packages holding roots, before their syntax is loaded", func() {
+		var holding []string
+		for _, pkg := range pkgs {
+			if (traverser.Roots{"api.RestClient.VolumeCreate", "storage_drivers/ontap/api/azgo/*.go", "utils/yaml.v3"}).Holds(workDir, pkg) {
+				holding = append(holding, pkg.Name)
+			}
+		}
+		Expect(holding).To(ConsistOf("api", "azgo", "yaml"))
+	})
+})
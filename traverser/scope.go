@@ -0,0 +1,107 @@
+package traverser
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/mod/modfile"
+)
+
+// Scope is the packages the traversal stays within, by import path: those under any of the prefixes,
+// unless under any of the exclusions.
+type Scope struct {
+	Prefixes   []string `json:"prefixes"`
+	Exclusions []string `json:"exclusions"`
+}
+
+// APIScope is the default scope walking down, the packages under the longest import path every operation package
+// is under, e.g. <module>/storage_drivers/ontap/api for Trident. It is empty if they share no path.
+func APIScope(operations Operations) Scope {
+	var common []string
+	for i, pkgPath := range operations.Packages() {
+		elements := strings.Split(pkgPath, "/")
+		if i == 0 {
+			common = elements
+			continue
+		}
+		n := 0
+		for n < len(common) && n < len(elements) && common[n] == elements[n] {
+			n++
+		}
+		common = common[:n]
+	}
+	if len(common) == 0 {
+		return Scope{}
+	}
+	return Scope{Prefixes: []string{strings.Join(common, "/")}}
+}
+
+// ModuleScope is the default scope walking up, the whole module.
+func ModuleScope(modulePath string) Scope {
+	return Scope{Prefixes: []string{modulePath}}
+}
+
+// Contains tells whether the package of the import path is within the scope.
+func (s Scope) Contains(importPath string) bool {
+	for _, exclusion := range s.Exclusions {
+		if under(importPath, exclusion) {
+			return false
+		}
+	}
+	for _, prefix := range s.Prefixes {
+		if under(importPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// ContainsDetail tells whether the package of the call hierarchy item is within the scope, its detail being
+// the import path of its package followed by its file name, e.g. github.com/netapp/trident/utils • utils.go
+func (s Scope) ContainsDetail(detail string) bool {
+	importPath, _, _ := strings.Cut(detail, " ")
+	return s.Contains(importPath)
+}
+
+func (s Scope) String() string {
+	if len(s.Exclusions) == 0 {
+		return strings.Join(s.Prefixes, ",")
+	}
+	return strings.Join(s.Prefixes, ",") + " except " + strings.Join(s.Exclusions, ",")
+}
+
+// under tells whether the import path is the prefix, or one of the packages below it.
+func under(importPath, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return importPath == prefix || strings.HasPrefix(importPath, prefix+"/")
+}
+
+// ModulePath returns the path of the module the directory belongs to, as the closest go.mod declares it.
+func ModulePath(dir string) (string, error) {
+	dir, err := filepath.Abs(strings.TrimSuffix(dir, "/..."))
+	if err != nil {
+		return "", fmt.Errorf("#ModulePath: failed to find the directory %s -> %w", dir, err)
+	}
+
+	for start := dir; ; {
+		content, err := os.ReadFile(filepath.Join(dir, "go.mod"))
+		if err == nil {
+			modulePath := modfile.ModulePath(content)
+			if modulePath == "" {
+				return "", fmt.Errorf("#ModulePath: no module declared in %s", filepath.Join(dir, "go.mod"))
+			}
+			return modulePath, nil
+		}
+		if !os.IsNotExist(err) {
+			return "", fmt.Errorf("#ModulePath: failed to read %s -> %w", filepath.Join(dir, "go.mod"), err)
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("#ModulePath: no go.mod in %s or above", start)
+		}
+		dir = parent
+	}
+}
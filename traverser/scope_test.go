@@ -0,0 +1,56 @@
+package traverser_test
+
+import (
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/theshashankpal/api-collector/traverser"
+)
+
+var _ = Describe("Scope", func() {
+	It("should contain the packages under its prefixes, but not under its exclusions", func() {
+		scope := traverser.Scope{
+			Prefixes:   []string{"example.com/fork/storage_drivers/ontap/api"},
+			Exclusions: []string{"example.com/fork/storage_drivers/ontap/api/azgo/"},
+		}
+
+		Expect(scope.Contains("example.com/fork/storage_drivers/ontap/api")).To(BeTrue())
+		Expect(scope.Contains("example.com/fork/storage_drivers/ontap/api/rest/client")).To(BeTrue())
+		Expect(scope.Contains("example.com/fork/storage_drivers/ontap/apiextra")).To(BeFalse())
+		Expect(scope.Contains("example.com/fork/storage_drivers/ontap/api/azgo")).To(BeFalse())
+		Expect(scope.Contains("example.com/fork/storage_drivers/ontap/api/azgo/mocks")).To(BeFalse())
+		Expect(scope.Contains("github.com/netapp/trident/storage_drivers/ontap/api")).To(BeFalse())
+	})
+
+	It("should tell the package of a call hierarchy item from its detail", func() {
+		scope := traverser.ModuleScope("example.com/fork")
+
+		Expect(scope.ContainsDetail("example.com/fork/utils • utils.go")).To(BeTrue())
+		Expect(scope.ContainsDetail("example.com/forked/utils • utils.go")).To(BeFalse())
+	})
+
+	It("should read the module path from the closest go.mod", func() {
+		workDir, err := filepath.Abs("ast-traverser/dfs/recurser/testdata/trident")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(traverser.ModulePath(workDir)).To(Equal("github.com/netapp/trident"))
+		Expect(traverser.ModulePath(workDir + "/...")).To(Equal("github.com/netapp/trident"))
+		Expect(traverser.ModulePath(filepath.Join(workDir, "storage_drivers/ontap/api"))).To(Equal("github.com/netapp/trident"))
+	})
+
+	It("should walk down the packages under the longest import path the operation packages share", func() {
+		Expect(traverser.APIScope(traverser.DefaultOperations("github.com/netapp/trident")).Prefixes).To(Equal([]string{
+			"github.com/netapp/trident/storage_drivers/ontap/api",
+		}))
+		Expect(traverser.APIScope(traverser.Operations{
+			REST: []string{"example.com/ontap/rest/client"},
+			ZAPI: []string{"example.com/ontap/zapi", "example.com/ontapi/zapi"},
+		}).Prefixes).To(Equal([]string{"example.com"}))
+		Expect(traverser.APIScope(traverser.Operations{
+			REST: []string{"example.com/ontap/rest/client"},
+			ZAPI: []string{"example.org/azgo"},
+		}).Prefixes).To(BeEmpty())
+	})
+})
@@ -4,5 +4,22 @@ import "context"
 
 type Traverser interface {
 	Initialize(ctx context.Context)
-	Traverse(ctx context.Context) (chan map[string][]string, chan map[string][]string)
+	Traverse(ctx context.Context) (chan map[string]Finding, chan map[string]Finding)
+}
+
+// Finding is what the traversal found about a function issuing an ONTAP call, a REST client operation or
+// an azgo ExecuteUsing method, keyed by its functionID ("file:line:character:name", zero-based).
+type Finding struct {
+	// API is the method and path pattern of the REST client operation, or the ZAPI command.
+	API []string
+	// Chains are the functionIDs of the functions from a root down to the operation, in call order, the interface
+	// methods called along the way included. Walking down finds them, the shortest one of every root reaching the
+	// operation, sorted by root.
+	Chains [][]string
+	// Roots are the functionIDs of every root reaching the operation, sorted, only found walking down.
+	Roots []string
+	// Callers are the functionIDs of every function reaching the operation, only found walking up.
+	Callers []string
+	// Distances are the fewest calls every root reaches the operation in, keyed by its functionID, only found with BFS.
+	Distances map[string]int
 }
@@ -4,8 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	. "github.com/theshashankpal/api-collector/logger"
+	. "github.com/theshashankpal/api-collector/traverser"
 	"os"
-	"strings"
 )
 
 var zap = LogFields{Key: "layer", Value: "zapi"}
@@ -13,28 +13,53 @@ var zap = LogFields{Key: "layer", Value: "zapi"}
 type ZAPICommands struct {
 	FunctionName string `json:"function_name"`
 	Command      string `json:"command"`
+	// Chains lead from the roots down to the function, they aren't found with -reverse.
+	Chains [][]ChainLink `json:"chains,omitempty"`
+	// Callers are only found with -reverse.
+	Callers []string `json:"callers,omitempty"`
+	// Roots are only found with -strategy=bfs, along with their shortest call distance to the command.
+	Roots map[string]int `json:"roots,omitempty"`
+}
+
+// RootZAPICommands are the ZAPI commands a root reaches.
+type RootZAPICommands struct {
+	Root     ChainLink `json:"root"`
+	Commands []string  `json:"zapi_commands"`
 }
 
 type ZAPICommandsList struct {
 	Commands []ZAPICommands `json:"zapi_commands"`
+	// ByRoot are the commands of every root, they aren't found with -reverse.
+	ByRoot []RootZAPICommands `json:"by_root,omitempty"`
 }
 
-func WriteZAPICommands(ctx context.Context, zapiCommandsMap map[string][]string, file *os.File) error {
+func WriteZAPICommands(ctx context.Context, zapiCommandsMap map[string]Finding, file *os.File) error {
 	// Write REST APIs to a file
 
 	zapiCommandsList := ZAPICommandsList{
 		Commands: make([]ZAPICommands, 0),
 	}
 
-	for key, value := range zapiCommandsMap {
-		functionName := strings.TrimSpace(strings.Split(key, ":")[3])
+	for key, finding := range zapiCommandsMap {
 		tempZAPICommand := ZAPICommands{
-			FunctionName: functionName,
-			Command:      value[0],
+			FunctionName: chainLink(key).FunctionName,
+			Command:      finding.API[0],
+			Chains:       callChains(finding.Chains),
+			Roots:        rootDistances(finding.Distances),
+		}
+		for _, caller := range finding.Callers {
+			tempZAPICommand.Callers = append(tempZAPICommand.Callers, callerName(caller))
 		}
 		zapiCommandsList.Commands = append(zapiCommandsList.Commands, tempZAPICommand)
 	}
 
+	roots, commands := apisByRoot(zapiCommandsMap, func(finding Finding) string {
+		return finding.API[0]
+	})
+	for _, root := range roots {
+		zapiCommandsList.ByRoot = append(zapiCommandsList.ByRoot, RootZAPICommands{Root: chainLink(root), Commands: commands[root]})
+	}
+
 	jsonData, err := json.MarshalIndent(zapiCommandsList, "", "    ")
 	if err != nil {
 		return err